@@ -2,95 +2,444 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"gogo-scheduler/internal/config"
 	"gogo-scheduler/internal/handler"
+	"gogo-scheduler/internal/logging"
+	"gogo-scheduler/internal/metrics"
 	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/oidc"
 	"gogo-scheduler/internal/repository"
 	"gogo-scheduler/internal/service"
-	"log"
+	"gogo-scheduler/internal/storage"
+	"gogo-scheduler/internal/tracing"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/app/server"
+	hertzconfig "github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/route"
 	"github.com/glebarez/sqlite"
 	"github.com/hertz-contrib/cors"
+	"github.com/panjf2000/ants/v2"
 	"gorm.io/gorm"
 )
 
 func main() {
-	// Ensure data directory exists
-	if err := os.MkdirAll("data", 0755); err != nil {
-		log.Fatal("Failed to create data directory:", err)
+	cfg, err := config.Load(os.Args[0], os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fatal("invalid configuration", "error", err)
+	}
+	if err := logging.Setup(os.Stderr, cfg.Log.Level, cfg.Log.Format); err != nil {
+		fatal("failed to initialize logging", "error", err)
+	}
+	if cfg.Auth.JWTSecret == config.DefaultJWTSecret {
+		slog.Warn("using the default JWT secret; set auth.jwt_secret before exposing the scheduler")
 	}
 
-	// Initialize database with new path
-	db, err := gorm.Open(sqlite.Open("data/scripts.db"), &gorm.Config{})
+	// Ensure the directory of the database file exists
+	if !strings.HasPrefix(cfg.Database.DSN, "file:") && cfg.Database.DSN != ":memory:" {
+		if err := os.MkdirAll(filepath.Dir(cfg.Database.DSN), 0755); err != nil {
+			fatal("failed to create data directory", "error", err)
+		}
+	}
+
+	// Export traces if an OTLP endpoint is configured
+	shutdownTracing, err := tracing.Setup(context.Background())
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		fatal("failed to initialize tracing", "error", err)
+	}
+
+	// Initialize database
+	db, err := gorm.Open(sqlite.Open(cfg.Database.DSN), &gorm.Config{
+		Logger: logging.GormLogger{SlowThreshold: 200 * time.Millisecond},
+	})
+	if err != nil {
+		fatal("failed to connect to database", "error", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		fatal("failed to connect to database", "error", err)
+	}
+	sqlDB.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		fatal("failed to initialize database tracing", "error", err)
 	}
 
 	// Auto migrate the schema
-	err = db.AutoMigrate(&model.Script{}, &model.Task{}, &model.User{})
+	models := []any{&model.Script{}, &model.Task{}, &model.User{}, &model.RetentionPolicy{}, &model.Artifact{}, &model.APIToken{},
+		&model.RefreshToken{}, &model.RevokedAccessToken{}, &model.RecoveryCode{}, &model.SecuritySettings{},
+		&model.AuditEvent{}, &model.ScriptShare{}, &model.Group{}, &model.GroupMember{},
+		&model.Project{}, &model.ProjectMember{}, &model.NotificationChannel{}, &model.ScriptNotification{}, &model.NotificationDelivery{}}
+	err = db.AutoMigrate(models...)
 	if err != nil {
-		log.Fatal("Failed to migrate database:", err)
+		fatal("failed to migrate database", "error", err)
 	}
 
 	// Initialize dependencies
 	scriptRepo := repository.NewScriptRepository(db)
 	taskRepo := repository.NewTaskRepository(db)
 	userRepo := repository.NewUserRepository(db)
-	scriptService := service.NewScriptService(scriptRepo, taskRepo)
-	authService := service.NewAuthService(userRepo, "your-secret-key") // Replace with environment variable in production
-	scriptHandler := handler.NewScriptHandler(scriptService)
-	taskHandler := handler.NewTaskHandler(scriptService)
-	authHandler := handler.NewAuthHandler(authService)
+	if err := userRepo.CreateAdminIfNotExists(); err != nil {
+		fatal("failed to create admin user", "error", err)
+	}
+	if err := scriptRepo.AssignOwnerless(); err != nil {
+		fatal("failed to assign script owners", "error", err)
+	}
+	retentionRepo := repository.NewRetentionRepository(db)
+	artifactRepo := repository.NewArtifactRepository(db)
+	apiTokenRepo := repository.NewAPITokenRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	twoFactorRepo := repository.NewTwoFactorRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	shareRepo := repository.NewShareRepository(db)
+	groupRepo := repository.NewGroupRepository(db)
+	projectRepo := repository.NewProjectRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	if _, err := projectRepo.CreateDefaultIfNotExists(); err != nil {
+		fatal("failed to create default project", "error", err)
+	}
+	// tasks cannot survive a restart and would otherwise count against quotas
+	if err := taskRepo.FailInterrupted(); err != nil {
+		fatal("failed to clean up interrupted tasks", "error", err)
+	}
+	// retries of notifications are not resumed after a restart
+	if err := notificationRepo.FailPendingDeliveries(); err != nil {
+		fatal("failed to clean up interrupted notification deliveries", "error", err)
+	}
+	logStore, err := storage.New(storeConfig(cfg.Storage, cfg.Storage.LogsDir, "logs/"))
+	if err != nil {
+		fatal("failed to initialize log storage", "error", err)
+	}
+	logService := service.NewLogService(logStore, cfg.Executor.CompressLogs, int(cfg.Executor.MaxLogSize))
+	artifactStore, err := storage.New(storeConfig(cfg.Storage, cfg.Storage.ArtifactsDir, "artifacts/"))
+	if err != nil {
+		fatal("failed to initialize artifact storage", "error", err)
+	}
+	artifactService := service.NewArtifactService(artifactRepo, artifactStore, int64(cfg.Executor.MaxArtifactSize), int64(cfg.Executor.MaxTaskArtifacts))
+	webhookNetworks, _ := cfg.Notifications.AllowedWebhookNetworks() // checked by config.Load
+	notificationService := service.NewNotificationService(notificationRepo, projectRepo, service.RetryConfig{
+		MaxAttempts: cfg.Notifications.MaxAttempts,
+		BaseDelay:   cfg.Notifications.BaseDelay,
+		MaxDelay:    cfg.Notifications.MaxDelay,
+	}, cfg.Server.PublicURL, webhookNetworks)
+	// Runs are executed in a pool of workers; 0 keeps the pool unbounded
+	poolSize := cfg.Workers.PoolSize
+	if poolSize == 0 {
+		poolSize = ants.DefaultAntsPoolSize
+	}
+	pool, err := ants.NewPool(poolSize)
+	if err != nil {
+		fatal("failed to create worker pool", "error", err)
+	}
+	metrics.RegisterWorkerPool(pool)
+	scriptService := service.NewScriptService(scriptRepo, taskRepo, shareRepo, groupRepo, userRepo, projectRepo, logService, artifactService, notificationService, pool, service.ExecutorConfig{
+		WorkspaceDir: cfg.Storage.WorkspacesDir,
+		ApprovalTTL:  cfg.Executor.ApprovalTTL,
+		Python:       cfg.Executor.Python,
+		Shell:        cfg.Executor.Shell,
+	})
+	authenticators := []service.Authenticator{service.NewLocalAuthenticator(userRepo)}
+	if cfg.Auth.LDAP.Enabled() {
+		ldapAuthenticator, err := service.NewLDAPAuthenticator(ldapConfig(cfg.Auth.LDAP), userRepo)
+		if err != nil {
+			fatal("failed to initialize LDAP authentication", "error", err)
+		}
+		authenticators = append(authenticators, ldapAuthenticator)
+	}
+	policy := passwordPolicy(cfg.Auth.Password)
+	authService := service.NewAuthService(userRepo, apiTokenRepo, sessionRepo, twoFactorRepo, service.AuthConfig{
+		JWTSecret:       cfg.Auth.JWTSecret,
+		AccessTokenTTL:  cfg.Auth.AccessTokenTTL,
+		RefreshTokenTTL: cfg.Auth.RefreshTokenTTL,
+		PasswordPolicy:  policy,
+		LoginThrottle:   service.DefaultThrottleConfig(),
+	}, authenticators...)
+	userService := service.NewUserService(userRepo, twoFactorRepo, policy)
+	groupService := service.NewGroupService(groupRepo, userRepo)
+	projectService := service.NewProjectService(projectRepo, userRepo)
+	retentionService := service.NewRetentionService(retentionRepo, taskRepo, logService, artifactService, scriptService)
+	auditService := service.NewAuditService(auditRepo)
+	// Background jobs, also checked for readiness
+	const retentionInterval, approvalExpiryInterval = time.Hour, time.Minute
+	healthService := service.NewHealthService(repository.NewHealthRepository(db), models, pool,
+		service.Loop{Name: "retention", Interval: retentionInterval, LastRun: retentionService.LastRun},
+		service.Loop{Name: "approval_expiry", Interval: approvalExpiryInterval, LastRun: scriptService.LastApprovalExpiry})
+	scriptHandler := handler.NewScriptHandler(scriptService, auditService)
+	taskHandler := handler.NewTaskHandler(scriptService, auditService)
+	authHandler := handler.NewAuthHandler(authService, auditService)
+	retentionHandler := handler.NewRetentionHandler(retentionService, auditService)
+	userHandler := handler.NewUserHandler(userService, auditService)
+	apiTokenHandler := handler.NewAPITokenHandler(authService, auditService)
+	twoFactorHandler := handler.NewTwoFactorHandler(authService, auditService)
+	auditHandler := handler.NewAuditHandler(auditService)
+	groupHandler := handler.NewGroupHandler(groupService, auditService)
+	projectHandler := handler.NewProjectHandler(projectService, auditService)
+	notificationHandler := handler.NewNotificationHandler(notificationService, auditService)
+	healthHandler := handler.NewHealthHandler(healthService)
+
+	// Apply task retention policies in the background
+	go retentionService.Start(context.Background(), retentionInterval)
+	// Expire runs that waited too long for approval
+	go scriptService.StartApprovalExpiry(context.Background(), approvalExpiryInterval)
 
 	// Setup Hertz server
-	h := server.Default(server.WithHostPorts("0.0.0.0:8080"))
+	options := []hertzconfig.Option{server.WithHostPorts(cfg.Server.Listen)}
+	if cfg.Server.TLS.Enabled() {
+		cert, err := tls.LoadX509KeyPair(cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile)
+		if err != nil {
+			fatal("failed to load TLS certificate", "error", err)
+		}
+		options = append(options, server.WithTLS(&tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}))
+	}
+	h := server.Default(options...)
+	// Client addresses, used to throttle logins and in the audit log, are
+	// only taken from forwarding headers set by trusted proxies
+	trustedProxies, _ := cfg.Server.TrustedProxyNetworks() // checked by config.Load
+	h.SetClientIPFunc(app.ClientIPWithOption(app.ClientIPOptions{
+		RemoteIPHeaders: []string{"X-Forwarded-For", "X-Real-IP"},
+		TrustedCIDRs:    trustedProxies,
+	}))
+	// Tracing comes first so that request logs carry the trace ID
+	h.Use(handler.TracingMiddleware(), handler.LoggingMiddleware(), handler.MetricsMiddleware())
+	h.OnShutdown = append(h.OnShutdown, func(ctx context.Context) {
+		notificationService.Stop()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.ErrorContext(ctx, "failed to flush traces", "error", err)
+		}
+	})
 
 	// CORS middleware
 	h.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},                                       // Allowed domains, need to bring schema
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}, // Allowed request methods
-		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type"}, // Allowed request headers
-		ExposeHeaders:    []string{"Content-Length"},                          // Request headers allowed in the upload_file
-		AllowCredentials: true,                                                // Whether cookies are attached
-		MaxAge:           36 * time.Hour,                                      // Maximum length of upload_file-side cache preflash requests (seconds)
+		AllowOrigins:     cfg.CORS.AllowedOrigins,                                                                  // Allowed domains, need to bring schema
+		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},                                      // Allowed request methods
+		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type", handler.RequestIDHeader},             // Allowed request headers
+		ExposeHeaders:    []string{"Content-Length", "Content-Disposition", "X-Log-Size", handler.RequestIDHeader}, // Request headers allowed in the upload_file
+		AllowCredentials: true,                                                                                     // Whether cookies are attached
+		MaxAge:           36 * time.Hour,                                                                           // Maximum length of upload_file-side cache preflash requests (seconds)
 	}))
 
-	h.LoadHTMLGlob("dist/index.html")
-	h.Static("/", "./dist")
+	h.LoadHTMLGlob(filepath.Join(cfg.Server.StaticDir, "index.html"))
+	h.Static("/", cfg.Server.StaticDir)
 	h.GET("/", func(c context.Context, ctx *app.RequestContext) {
 		ctx.HTML(200, "index.html", nil)
 	})
 	// Serve index.html for all other routes
 	h.NoRoute(func(ctx context.Context, c *app.RequestContext) {
-		c.File(filepath.Join("dist", "index.html"))
+		c.File(filepath.Join(cfg.Server.StaticDir, "index.html"))
 	})
 
 	// no auth
+	h.GET("/metrics", handler.Metrics())
+	h.GET("/healthz", healthHandler.Live)
+	h.GET("/readyz", healthHandler.Ready)
 	h.POST("/api/auth/login", authHandler.Login)
+	h.POST("/api/auth/refresh", authHandler.Refresh)
+	h.POST("/api/auth/2fa/verify", twoFactorHandler.Verify)
+	if cfg.Auth.OIDC.Enabled() {
+		ssoService := service.NewSSOService(ssoConfig(cfg.Auth.OIDC), userRepo, authService)
+		ssoHandler := handler.NewSSOHandler(ssoService, auditService, cfg.Auth.OIDC.PostLoginRedirect)
+		h.GET("/api/auth/oidc/login", ssoHandler.Login)
+		h.GET("/api/auth/oidc/callback", ssoHandler.Callback)
+	}
 
 	g := h.Group("/api/", handler.AuthMiddleware(authService))
 	// Define routes
 	// Auth routes
 	g.POST("/auth/change-password", authHandler.ChangePassword)
-	// Script routes
-	g.POST("/scripts", scriptHandler.CreateScript)
-	g.GET("/scripts", scriptHandler.ListScripts)
-	g.GET("/scripts/:id", scriptHandler.GetScript)
-	g.PUT("/scripts/:id", scriptHandler.UpdateScript)
-	g.POST("/scripts/:id/run", scriptHandler.RunScript)
-	g.DELETE("/scripts/:id", scriptHandler.DeleteScript)
-
-	// Task routes
-	g.GET("/tasks", taskHandler.ListTasks)
-	g.GET("/tasks/:id", taskHandler.GetTask)
-	g.DELETE("/tasks/:id", taskHandler.DeleteTask)
-	g.POST("/tasks/:id/rerun", taskHandler.RerunTask)
+	g.POST("/auth/logout", authHandler.Logout)
+	// Two-factor authentication routes
+	g.POST("/auth/2fa/enroll", twoFactorHandler.Enroll)
+	g.POST("/auth/2fa/activate", twoFactorHandler.Activate)
+	g.POST("/auth/2fa/disable", twoFactorHandler.Disable)
+	g.POST("/auth/2fa/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes)
+	// Personal API token routes
+	g.GET("/tokens", apiTokenHandler.ListTokens)
+	g.POST("/tokens", apiTokenHandler.CreateToken)
+	g.DELETE("/tokens/:id", apiTokenHandler.RevokeToken)
+	// Permission checks, layered after AuthMiddleware
+	canRead := handler.RequirePermission(model.PermissionRead)
+	canRun := handler.RequirePermission(model.PermissionRun)
+	canEdit := handler.RequirePermission(model.PermissionEdit)
+	isAdmin := handler.RequirePermission(model.PermissionAdminister)
+	inProject := handler.ProjectMiddleware(projectService)
+
+	projectRoutes := func(r *route.RouterGroup) {
+		// Script routes
+		r.POST("/scripts", canEdit, scriptHandler.CreateScript)
+		r.GET("/scripts", canRead, scriptHandler.ListScripts)
+		r.GET("/scripts/:id", canRead, scriptHandler.GetScript)
+		r.PUT("/scripts/:id", canEdit, scriptHandler.UpdateScript)
+		r.POST("/scripts/:id/run", canRun, scriptHandler.RunScript)
+		r.DELETE("/scripts/:id", canEdit, scriptHandler.DeleteScript)
+		r.GET("/scripts/:id/shares", canRead, scriptHandler.ListShares)
+		r.POST("/scripts/:id/shares", canEdit, scriptHandler.ShareScript)
+		r.DELETE("/scripts/:id/shares/:share_id", canEdit, scriptHandler.UnshareScript)
+		r.PUT("/scripts/:id/owner", canEdit, scriptHandler.TransferOwnership)
+		r.GET("/scripts/:id/notifications", canRead, scriptHandler.GetNotifications)
+		r.PUT("/scripts/:id/notifications", canEdit, scriptHandler.SetNotifications)
+
+		// Task routes
+		r.GET("/tasks", canRead, taskHandler.ListTasks)
+		r.GET("/tasks/:id", canRead, taskHandler.GetTask)
+		r.GET("/tasks/:id/logs", canRead, taskHandler.GetTaskLogs)
+		r.GET("/tasks/:id/artifacts", canRead, taskHandler.ListArtifacts)
+		r.GET("/tasks/:id/artifacts/:artifact_id", canRead, taskHandler.DownloadArtifact)
+		r.DELETE("/tasks/:id", canEdit, taskHandler.DeleteTask)
+		r.POST("/tasks/:id/rerun", canRun, taskHandler.RerunTask)
+		r.POST("/tasks/:id/approve", canRun, taskHandler.ApproveTask)
+		r.POST("/tasks/:id/reject", canRun, taskHandler.RejectTask)
+
+		// Notification channel routes; channel URLs can hold credentials
+		r.GET("/notification-channels", canEdit, notificationHandler.ListChannels)
+		r.POST("/notification-channels", isAdmin, notificationHandler.CreateChannel)
+		r.GET("/notification-channels/:id", canEdit, notificationHandler.GetChannel)
+		r.PUT("/notification-channels/:id", isAdmin, notificationHandler.UpdateChannel)
+		r.DELETE("/notification-channels/:id", isAdmin, notificationHandler.DeleteChannel)
+		r.POST("/notification-channels/:id/test", isAdmin, notificationHandler.TestChannel)
+		r.GET("/notification-channels/:id/deliveries", canEdit, notificationHandler.ListDeliveries)
+
+		// Retention routes limited to the project and its scripts
+		r.GET("/scripts/:id/retention", canRead, retentionHandler.GetScriptPolicy)
+		r.PUT("/scripts/:id/retention", canEdit, retentionHandler.UpdateScriptPolicy)
+		r.DELETE("/scripts/:id/retention", canEdit, retentionHandler.DeleteScriptPolicy)
+		r.POST("/retention/dry-run", isAdmin, retentionHandler.DryRun)
+		r.POST("/retention/run", isAdmin, retentionHandler.Run)
+	}
+	// Routes outside /projects/:project act on the default project
+	projectRoutes(g.Group("", inProject))
+	projectRoutes(g.Group("/projects/:project", inProject))
+
+	// Project routes; permissions after inProject are checked against the
+	// user's role in the project
+	g.GET("/projects", projectHandler.ListProjects)
+	g.POST("/projects", isAdmin, projectHandler.CreateProject)
+	g.GET("/projects/:project", inProject, canRead, projectHandler.GetProject)
+	g.PUT("/projects/:project", inProject, isAdmin, projectHandler.UpdateProject)
+	g.DELETE("/projects/:project", isAdmin, inProject, projectHandler.DeleteProject)
+	g.GET("/projects/:project/members", inProject, canRead, projectHandler.ListMembers)
+	g.PUT("/projects/:project/members/:user_id", inProject, isAdmin, projectHandler.SetMember)
+	g.DELETE("/projects/:project/members/:user_id", inProject, isAdmin, projectHandler.RemoveMember)
+
+	// Global retention policy routes
+	g.GET("/retention/policy", canRead, retentionHandler.GetGlobalPolicy)
+	g.PUT("/retention/policy", isAdmin, retentionHandler.UpdateGlobalPolicy)
+
+	// User administration routes
+	g.GET("/users", isAdmin, userHandler.ListUsers)
+	g.POST("/users", isAdmin, userHandler.CreateUser)
+	g.PUT("/users/:id/role", isAdmin, userHandler.UpdateRole)
+	g.POST("/users/:id/disable", isAdmin, userHandler.DisableUser)
+	g.POST("/users/:id/enable", isAdmin, userHandler.EnableUser)
+	g.POST("/users/:id/reset-password", isAdmin, userHandler.ResetPassword)
+	g.DELETE("/users/:id", isAdmin, userHandler.DeleteUser)
+	g.DELETE("/users/:id/2fa", isAdmin, userHandler.ResetTwoFactor)
+	g.GET("/settings/security", isAdmin, twoFactorHandler.GetSettings)
+	g.PUT("/settings/security", isAdmin, twoFactorHandler.UpdateSettings)
+
+	// Group routes
+	g.GET("/groups", canRead, groupHandler.ListGroups)
+	g.POST("/groups", isAdmin, groupHandler.CreateGroup)
+	g.DELETE("/groups/:id", isAdmin, groupHandler.DeleteGroup)
+	g.POST("/groups/:id/members", isAdmin, groupHandler.AddMember)
+	g.DELETE("/groups/:id/members/:user_id", isAdmin, groupHandler.RemoveMember)
+
+	// Audit log routes
+	g.GET("/audit", isAdmin, auditHandler.ListEvents)
+	g.GET("/audit/export", isAdmin, auditHandler.ExportEvents)
+
 	// Start server
 	if err := h.Run(); err != nil {
-		log.Fatal("Failed to start server:", err)
+		fatal("failed to start server", "error", err)
+	}
+}
+
+// fatal logs why the scheduler cannot start and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// storeConfig returns the settings of a store kept in dir with the local
+// backend, or below prefix in the bucket with S3.
+func storeConfig(cfg config.StorageConfig, dir, prefix string) storage.Config {
+	return storage.Config{
+		Backend: cfg.Backend,
+		Dir:     dir,
+		S3: storage.S3Config{
+			Endpoint:  cfg.S3.Endpoint,
+			Region:    cfg.S3.Region,
+			Bucket:    cfg.S3.Bucket,
+			AccessKey: cfg.S3.AccessKey,
+			SecretKey: cfg.S3.SecretKey,
+			Prefix:    cfg.S3.Prefix + prefix,
+		},
+	}
+}
+
+// passwordPolicy returns the password rules for local accounts.
+func passwordPolicy(cfg config.PasswordConfig) service.PasswordPolicy {
+	policy := service.DefaultPasswordPolicy()
+	policy.MinLength = cfg.MinLength
+	policy.RequireUpper = cfg.RequireUpper
+	policy.RequireLower = cfg.RequireLower
+	policy.RequireDigit = cfg.RequireDigit
+	policy.RequireSymbol = cfg.RequireSymbol
+	return policy
+}
+
+// ssoConfig returns the OpenID Connect settings of single sign-on.
+func ssoConfig(cfg config.OIDCConfig) service.SSOConfig {
+	return service.SSOConfig{
+		OIDC: oidc.Config{
+			Issuer:       cfg.Issuer,
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       cfg.Scopes,
+		},
+		GroupsClaim:   cfg.GroupsClaim,
+		RoleMapping:   roleMapping(cfg.RoleMapping),
+		DefaultRole:   model.Role(cfg.DefaultRole),
+		AutoProvision: cfg.AutoProvision,
+	}
+}
+
+// ldapConfig returns the settings of LDAP authentication.
+func ldapConfig(cfg config.LDAPConfig) service.LDAPConfig {
+	return service.LDAPConfig{
+		URL:                cfg.URL,
+		StartTLS:           cfg.StartTLS,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		CAFile:             cfg.CAFile,
+		Timeout:            cfg.Timeout,
+		BindDN:             cfg.BindDN,
+		BindPassword:       cfg.BindPassword,
+		BaseDN:             cfg.BaseDN,
+		UserFilter:         cfg.UserFilter,
+		UsernameAttribute:  cfg.UsernameAttribute,
+		GroupBaseDN:        cfg.GroupBaseDN,
+		GroupFilter:        cfg.GroupFilter,
+		GroupAttribute:     cfg.GroupAttribute,
+		RoleMapping:        roleMapping(cfg.RoleMapping),
+		DefaultRole:        model.Role(cfg.DefaultRole),
+	}
+}
+
+func roleMapping(mapping config.RoleMapping) map[string]model.Role {
+	roles := make(map[string]model.Role, len(mapping))
+	for group, role := range mapping {
+		roles[group] = model.Role(role)
 	}
+	return roles
 }
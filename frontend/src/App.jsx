@@ -14,6 +14,8 @@ import { Toaster, toast } from 'react-hot-toast';
 function App() {
   const navigate = useNavigate();
   const [tasks, setTasks] = useState([]);
+  const [taskPage, setTaskPage] = useState(1);
+  const [taskTotal, setTaskTotal] = useState(0);
   const [scripts, setScripts] = useState([]);
   const [isScriptDialogOpen, setIsScriptDialogOpen] = useState(false);
   const [isChangePasswordDialogOpen, setIsChangePasswordDialogOpen] = useState(false);
@@ -27,6 +29,8 @@ function App() {
     { label: '30s', value: 30000 },
   ];
   const [refreshInterval, setRefreshInterval] = useState(5000);
+  const TASK_PAGE_SIZE = 50;
+  const taskPages = Math.max(1, Math.ceil(taskTotal / TASK_PAGE_SIZE));
 
   // Modify API_BASE_URL configuration
   const API_BASE_URL = import.meta.env.PROD ? '/api' : 'http://localhost:8080/api';
@@ -43,7 +47,7 @@ function App() {
     return () => {
       if (timer) clearInterval(timer);
     };
-  }, [refreshInterval]);
+  }, [refreshInterval, taskPage]);
 
   const getErrorMessage = async (response) => {
     try {
@@ -62,7 +66,7 @@ function App() {
 
   const fetchTasks = async () => {
     try {
-      const response = await fetch(`${API_BASE_URL}/tasks`, {
+      const response = await fetch(`${API_BASE_URL}/tasks?page=${taskPage}&page_size=${TASK_PAGE_SIZE}`, {
         method: 'GET',
         headers: {
           'Content-Type': 'application/json',
@@ -74,16 +78,19 @@ function App() {
         throw new Error(errorMessage);
       }
       const data = await response.json();
-      setTasks(data);
+      setTasks(data.items);
+      setTaskTotal(data.total);
     } catch (error) {
       toast.error(error.message);
       console.error('Error fetching tasks:', error);
     }
   };
 
-  const fetchScripts = async () => {
-    try {
-      const response = await fetch(`${API_BASE_URL}/scripts`, {
+  // Lists are paginated; read every page so nothing is left out
+  const fetchAllPages = async (path) => {
+    const items = [];
+    for (let page = 1; ; page++) {
+      const response = await fetch(`${API_BASE_URL}${path}?page=${page}&page_size=100`, {
         method: 'GET',
         headers: {
           'Content-Type': 'application/json',
@@ -95,7 +102,16 @@ function App() {
         throw new Error(errorMessage);
       }
       const data = await response.json();
-      setScripts(data);
+      items.push(...data.items);
+      if (data.items.length === 0 || items.length >= data.total) {
+        return items;
+      }
+    }
+  };
+
+  const fetchScripts = async () => {
+    try {
+      setScripts(await fetchAllPages('/scripts'));
     } catch (error) {
       toast.error(error.message);
       console.error('Error fetching scripts:', error);
@@ -383,6 +399,27 @@ function App() {
             </div>
           </div>
           <TaskList tasks={tasks} onDelete={handleDeleteTask} onRerun={handleRerunTask} />
+          {taskTotal > TASK_PAGE_SIZE && (
+            <div className="flex justify-between items-center mt-4 text-sm text-gray-700">
+              <span>{taskTotal} tasks, page {taskPage} of {taskPages}</span>
+              <div className="space-x-2">
+                <button
+                  onClick={() => setTaskPage(taskPage - 1)}
+                  disabled={taskPage <= 1}
+                  className="px-3 py-1 border border-gray-300 rounded hover:bg-gray-100 disabled:opacity-50"
+                >
+                  Previous
+                </button>
+                <button
+                  onClick={() => setTaskPage(taskPage + 1)}
+                  disabled={taskPage >= taskPages}
+                  className="px-3 py-1 border border-gray-300 rounded hover:bg-gray-100 disabled:opacity-50"
+                >
+                  Next
+                </button>
+              </div>
+            </div>
+          )}
         </div>
       </div>
 
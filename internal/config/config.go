@@ -0,0 +1,611 @@
+// Package config loads the settings of the scheduler. Settings come from
+// the defaults, a YAML or TOML file, environment variables and command-line
+// flags, each overriding the ones before.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"gogo-scheduler/internal/model"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ModeDevelopment = "development"
+	ModeProduction  = "production"
+)
+
+// DefaultJWTSecret is the JWT secret used when none is configured. It is
+// public, so the scheduler refuses to start with it in production mode.
+const DefaultJWTSecret = "your-secret-key"
+
+// minProductionSecretLength is the minimum length of the JWT secret in
+// production mode, as for an HMAC-SHA256 key.
+const minProductionSecretLength = 32
+
+// Config holds all settings. Every setting has a key, its path in the file
+// and the name of its flag, and most an environment variable.
+type Config struct {
+	Mode          string              `key:"mode" env:"SCHEDULER_MODE" help:"development or production; production refuses the default JWT secret"`
+	Server        ServerConfig        `key:"server"`
+	Database      DatabaseConfig      `key:"database"`
+	Auth          AuthConfig          `key:"auth"`
+	CORS          CORSConfig          `key:"cors"`
+	Workers       WorkersConfig       `key:"workers"`
+	Storage       StorageConfig       `key:"storage"`
+	Executor      ExecutorConfig      `key:"executor"`
+	Notifications NotificationsConfig `key:"notifications"`
+	Log           LogConfig           `key:"log"`
+}
+
+type ServerConfig struct {
+	Listen         string    `key:"listen" env:"LISTEN_ADDR" help:"address to listen on"`
+	PublicURL      string    `key:"public_url" env:"PUBLIC_URL" help:"URL the scheduler is reached at, e.g. https://scheduler.example.com"`
+	StaticDir      string    `key:"static_dir" env:"STATIC_DIR" help:"directory of the built frontend"`
+	TrustedProxies []string  `key:"trusted_proxies" env:"TRUSTED_PROXIES" help:"comma-separated addresses or CIDRs of reverse proxies whose X-Forwarded-For and X-Real-IP headers are trusted"`
+	TLS            TLSConfig `key:"tls"`
+}
+
+// TrustedProxyNetworks parses TrustedProxies.
+func (c ServerConfig) TrustedProxyNetworks() ([]*net.IPNet, error) {
+	return parseNetworks(c.TrustedProxies)
+}
+
+// parseNetworks parses addresses and CIDRs. Single addresses are returned
+// as networks holding only that address.
+func parseNetworks(list []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, item := range list {
+		if ip := net.ParseIP(item); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, fmt.Errorf("%q is neither an address nor a CIDR", item)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// TLSConfig enables HTTPS when both files are set.
+type TLSConfig struct {
+	CertFile string `key:"cert_file" env:"TLS_CERT_FILE" help:"PEM certificate chain for HTTPS"`
+	KeyFile  string `key:"key_file" env:"TLS_KEY_FILE" help:"PEM private key for HTTPS"`
+}
+
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+type DatabaseConfig struct {
+	DSN          string `key:"dsn" env:"DATABASE_DSN" help:"SQLite database file or file: URI"`
+	MaxOpenConns int    `key:"max_open_conns" env:"DATABASE_MAX_OPEN_CONNS" help:"maximum open connections, 0 for no limit"`
+	MaxIdleConns int    `key:"max_idle_conns" env:"DATABASE_MAX_IDLE_CONNS" help:"maximum idle connections"`
+}
+
+type AuthConfig struct {
+	JWTSecret       string         `key:"jwt_secret" env:"JWT_SECRET" help:"secret signing access tokens"`
+	AccessTokenTTL  time.Duration  `key:"access_token_ttl" env:"ACCESS_TOKEN_TTL" help:"lifetime of access tokens"`
+	RefreshTokenTTL time.Duration  `key:"refresh_token_ttl" env:"REFRESH_TOKEN_TTL" help:"lifetime of refresh tokens"`
+	Password        PasswordConfig `key:"password"`
+	OIDC            OIDCConfig     `key:"oidc"`
+	LDAP            LDAPConfig     `key:"ldap"`
+}
+
+// PasswordConfig is the password policy of local accounts.
+type PasswordConfig struct {
+	MinLength     int  `key:"min_length" env:"PASSWORD_MIN_LENGTH" help:"minimum password length"`
+	RequireUpper  bool `key:"require_upper" env:"PASSWORD_REQUIRE_UPPER" help:"require an upper case letter"`
+	RequireLower  bool `key:"require_lower" env:"PASSWORD_REQUIRE_LOWER" help:"require a lower case letter"`
+	RequireDigit  bool `key:"require_digit" env:"PASSWORD_REQUIRE_DIGIT" help:"require a digit"`
+	RequireSymbol bool `key:"require_symbol" env:"PASSWORD_REQUIRE_SYMBOL" help:"require a character that is neither a letter nor a digit"`
+}
+
+// OIDCConfig enables single sign-on through an OpenID Connect provider when
+// Issuer is set.
+type OIDCConfig struct {
+	Issuer            string      `key:"issuer" env:"OIDC_ISSUER" help:"issuer URL of the OpenID Connect provider; enables single sign-on"`
+	ClientID          string      `key:"client_id" env:"OIDC_CLIENT_ID" help:"client ID"`
+	ClientSecret      string      `key:"client_secret" env:"OIDC_CLIENT_SECRET" help:"client secret"`
+	RedirectURL       string      `key:"redirect_url" env:"OIDC_REDIRECT_URL" help:"callback URL, e.g. https://scheduler.example.com/api/auth/oidc/callback"`
+	Scopes            []string    `key:"scopes" env:"OIDC_SCOPES" help:"scopes to request; openid is always requested"`
+	GroupsClaim       string      `key:"groups_claim" env:"OIDC_GROUPS_CLAIM" help:"ID token claim listing the user's groups"`
+	RoleMapping       RoleMapping `key:"role_mapping" env:"OIDC_ROLE_MAPPING" help:"group=role pairs, e.g. developers=editor,platform-admins=admin"`
+	DefaultRole       string      `key:"default_role" env:"OIDC_DEFAULT_ROLE" help:"role of users whose groups are not mapped"`
+	AutoProvision     bool        `key:"auto_provision" env:"OIDC_AUTO_PROVISION" help:"create users on their first login"`
+	PostLoginRedirect string      `key:"post_login_redirect" env:"OIDC_POST_LOGIN_REDIRECT" help:"frontend URL to return to after login, / if empty"`
+}
+
+func (c OIDCConfig) Enabled() bool {
+	return c.Issuer != ""
+}
+
+// LDAPConfig enables LDAP passwords when URL is set.
+type LDAPConfig struct {
+	URL                string        `key:"url" env:"LDAP_URL" help:"ldap://host:389 or ldaps://host:636; enables LDAP authentication"`
+	StartTLS           bool          `key:"start_tls" env:"LDAP_START_TLS" help:"upgrade ldap:// connections with StartTLS"`
+	CAFile             string        `key:"ca_file" env:"LDAP_CA_FILE" help:"PEM file of the CA certificates to trust"`
+	InsecureSkipVerify bool          `key:"insecure_skip_verify" env:"LDAP_INSECURE_SKIP_VERIFY" help:"skip certificate verification, for testing only"`
+	Timeout            time.Duration `key:"timeout" env:"LDAP_TIMEOUT" help:"timeout of connections and searches"`
+	BindDN             string        `key:"bind_dn" env:"LDAP_BIND_DN" help:"service account searching for users; anonymous if empty"`
+	BindPassword       string        `key:"bind_password" env:"LDAP_BIND_PASSWORD" help:"password of the service account"`
+	BaseDN             string        `key:"base_dn" env:"LDAP_BASE_DN" help:"where to search for users"`
+	UserFilter         string        `key:"user_filter" env:"LDAP_USER_FILTER" help:"filter finding a user, %s is the username; (sAMAccountName=%s) for Active Directory"`
+	UsernameAttribute  string        `key:"username_attribute" env:"LDAP_USERNAME_ATTRIBUTE" help:"attribute holding the username"`
+	GroupBaseDN        string        `key:"group_base_dn" env:"LDAP_GROUP_BASE_DN" help:"where to search for groups; only memberOf is used if empty"`
+	GroupFilter        string        `key:"group_filter" env:"LDAP_GROUP_FILTER" help:"filter finding the user's groups, %s is the user DN"`
+	GroupAttribute     string        `key:"group_attribute" env:"LDAP_GROUP_ATTRIBUTE" help:"attribute holding the group name"`
+	RoleMapping        RoleMapping   `key:"role_mapping" env:"LDAP_ROLE_MAPPING" help:"group name or DN=role pairs, separated by ; when using DNs"`
+	DefaultRole        string        `key:"default_role" env:"LDAP_DEFAULT_ROLE" help:"role of users whose groups are not mapped"`
+}
+
+func (c LDAPConfig) Enabled() bool {
+	return c.URL != ""
+}
+
+type CORSConfig struct {
+	AllowedOrigins []string `key:"allowed_origins" env:"CORS_ALLOWED_ORIGINS" help:"comma-separated origins allowed to call the API, or *"`
+}
+
+type WorkersConfig struct {
+	PoolSize int `key:"pool_size" env:"WORKER_POOL_SIZE" help:"maximum runs executed at once, 0 for no limit"`
+}
+
+type StorageConfig struct {
+	Backend       string   `key:"backend" env:"STORAGE_BACKEND" help:"local or s3, where task logs and artifacts are stored"`
+	LogsDir       string   `key:"logs_dir" env:"LOGS_DIR" help:"directory of task logs with the local backend"`
+	ArtifactsDir  string   `key:"artifacts_dir" env:"ARTIFACTS_DIR" help:"directory of task artifacts with the local backend"`
+	WorkspacesDir string   `key:"workspaces_dir" env:"WORKSPACES_DIR" help:"directory tasks run in"`
+	S3            S3Config `key:"s3"`
+}
+
+type S3Config struct {
+	Endpoint  string `key:"endpoint" env:"S3_ENDPOINT" help:"S3-compatible endpoint, e.g. https://s3.amazonaws.com"`
+	Region    string `key:"region" env:"S3_REGION" help:"bucket region, us-east-1 if empty"`
+	Bucket    string `key:"bucket" env:"S3_BUCKET" help:"bucket of task logs and artifacts"`
+	AccessKey string `key:"access_key" env:"S3_ACCESS_KEY" help:"access key ID"`
+	SecretKey string `key:"secret_key" env:"S3_SECRET_KEY" help:"secret access key"`
+	Prefix    string `key:"prefix" env:"S3_PREFIX" help:"key prefix, followed by logs/ or artifacts/"`
+}
+
+type ExecutorConfig struct {
+	Python           string        `key:"python" env:"PYTHON_INTERPRETER" help:"Python interpreter; python3, or python on Windows, if empty"`
+	Shell            string        `key:"shell" env:"SHELL_INTERPRETER" help:"interpreter of shell scripts"`
+	ApprovalTTL      time.Duration `key:"approval_ttl" env:"APPROVAL_TTL" help:"how long runs wait for approval"`
+	MaxLogSize       ByteSize      `key:"max_log_size" env:"MAX_LOG_SIZE" help:"output kept per task, e.g. 10MiB"`
+	CompressLogs     bool          `key:"compress_logs" env:"COMPRESS_LOGS" help:"gzip stored task logs"`
+	MaxArtifactSize  ByteSize      `key:"max_artifact_size" env:"MAX_ARTIFACT_SIZE" help:"largest artifact file kept, 0 for no limit"`
+	MaxTaskArtifacts ByteSize      `key:"max_task_artifacts" env:"MAX_TASK_ARTIFACTS" help:"total artifact size kept per task, 0 for no limit"`
+}
+
+type NotificationsConfig struct {
+	MaxAttempts     int           `key:"max_attempts" env:"NOTIFICATION_MAX_ATTEMPTS" help:"attempts per notification"`
+	BaseDelay       time.Duration `key:"base_delay" env:"NOTIFICATION_BASE_DELAY" help:"delay before the first retry, doubled on every retry"`
+	MaxDelay        time.Duration `key:"max_delay" env:"NOTIFICATION_MAX_DELAY" help:"longest delay between retries"`
+	AllowedNetworks []string      `key:"allowed_networks" env:"NOTIFICATION_ALLOWED_NETWORKS" help:"comma-separated addresses or CIDRs of internal webhook destinations to allow"`
+}
+
+// AllowedWebhookNetworks parses AllowedNetworks.
+func (c NotificationsConfig) AllowedWebhookNetworks() ([]*net.IPNet, error) {
+	return parseNetworks(c.AllowedNetworks)
+}
+
+type LogConfig struct {
+	Level  string `key:"level" env:"LOG_LEVEL" help:"debug, info, warn or error"`
+	Format string `key:"format" env:"LOG_FORMAT" help:"text or json"`
+}
+
+// Default returns the settings used when nothing is configured.
+func Default() *Config {
+	return &Config{
+		Mode: ModeDevelopment,
+		Server: ServerConfig{
+			Listen:    "0.0.0.0:8080",
+			StaticDir: "dist",
+		},
+		Database: DatabaseConfig{
+			DSN:          "data/scripts.db",
+			MaxIdleConns: 2,
+		},
+		Auth: AuthConfig{
+			JWTSecret:       DefaultJWTSecret,
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: 7 * 24 * time.Hour,
+			Password:        PasswordConfig{MinLength: 8},
+			OIDC: OIDCConfig{
+				Scopes:      []string{"openid", "profile", "email"},
+				GroupsClaim: "groups",
+				DefaultRole: string(model.RoleViewer),
+			},
+			LDAP: LDAPConfig{
+				Timeout:           10 * time.Second,
+				UserFilter:        "(uid=%s)",
+				UsernameAttribute: "uid",
+				GroupFilter:       "(member=%s)",
+				GroupAttribute:    "cn",
+				DefaultRole:       string(model.RoleViewer),
+			},
+		},
+		CORS: CORSConfig{AllowedOrigins: []string{"*"}},
+		Storage: StorageConfig{
+			Backend:       "local",
+			LogsDir:       "data/logs",
+			ArtifactsDir:  "data/artifacts",
+			WorkspacesDir: "data/workspaces",
+		},
+		Executor: ExecutorConfig{
+			Shell:            "bash",
+			ApprovalTTL:      24 * time.Hour,
+			MaxLogSize:       10 << 20,
+			CompressLogs:     true,
+			MaxArtifactSize:  100 << 20,
+			MaxTaskArtifacts: 500 << 20,
+		},
+		Notifications: NotificationsConfig{
+			MaxAttempts: 5,
+			BaseDelay:   2 * time.Second,
+			MaxDelay:    time.Minute,
+		},
+		Log: LogConfig{Level: "info", Format: "text"},
+	}
+}
+
+// Load returns the defaults overridden by the file given with -config or
+// CONFIG_FILE, the environment and the flags in args, and validates them.
+// It returns flag.ErrHelp if args ask for help.
+func Load(name string, args []string) (*Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	file := fs.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML configuration file (CONFIG_FILE)")
+	// flags are applied after the file and the environment
+	var flags []func()
+	cfg.visit(func(key string, field reflect.Value, tag reflect.StructTag) {
+		usage := tag.Get("help")
+		if env := tag.Get("env"); env != "" {
+			usage += " (" + env + ")"
+		}
+		fs.Func(key, usage, func(s string) error {
+			value := reflect.New(field.Type()).Elem()
+			if err := parse(value, s); err != nil {
+				return err
+			}
+			flags = append(flags, func() { field.Set(value) })
+			return nil
+		})
+	})
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *file != "" {
+		if err := cfg.loadFile(*file); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+	for _, set := range flags {
+		set()
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(name string) error {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+
+	settings := map[string]any{}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &settings)
+	case ".toml":
+		err = toml.Unmarshal(data, &settings)
+	default:
+		return fmt.Errorf("%s: unsupported configuration file type, use .yaml, .yml or .toml", name)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if err := apply(reflect.ValueOf(c).Elem(), "", settings); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+func (c *Config) loadEnv() error {
+	var errs []error
+	c.visit(func(key string, field reflect.Value, tag reflect.StructTag) {
+		env := tag.Get("env")
+		if env == "" {
+			return
+		}
+		if v := os.Getenv(env); v != "" {
+			if err := parse(field, v); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", env, err))
+			}
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// Validate reports every invalid setting.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: "+format, append([]any{key}, args...)...))
+	}
+
+	switch c.Mode {
+	case ModeDevelopment:
+	case ModeProduction:
+		if c.Auth.JWTSecret == DefaultJWTSecret {
+			invalid("auth.jwt_secret", "must be changed from the default in production mode")
+		} else if len(c.Auth.JWTSecret) < minProductionSecretLength {
+			invalid("auth.jwt_secret", "must be at least %d characters in production mode", minProductionSecretLength)
+		}
+		// the API allows credentials, so any site could call it as the user
+		if slices.Contains(c.CORS.AllowedOrigins, "*") {
+			invalid("cors.allowed_origins", "must list the allowed origins instead of * in production mode")
+		}
+	default:
+		invalid("mode", "must be %s or %s", ModeDevelopment, ModeProduction)
+	}
+
+	if _, _, err := net.SplitHostPort(c.Server.Listen); err != nil {
+		invalid("server.listen", "%v", err)
+	}
+	if c.Server.PublicURL != "" && !isHTTPURL(c.Server.PublicURL) {
+		invalid("server.public_url", "must be an http or https URL")
+	}
+	if _, err := c.Server.TrustedProxyNetworks(); err != nil {
+		invalid("server.trusted_proxies", "%v", err)
+	}
+	if c.Server.TLS.Enabled() {
+		if c.Server.TLS.CertFile == "" || c.Server.TLS.KeyFile == "" {
+			invalid("server.tls", "cert_file and key_file must both be set")
+		}
+	}
+
+	if c.Database.DSN == "" {
+		invalid("database.dsn", "must be set")
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		invalid("database", "connection limits must not be negative")
+	}
+
+	if c.Auth.JWTSecret == "" {
+		invalid("auth.jwt_secret", "must be set")
+	}
+	if c.Auth.AccessTokenTTL <= 0 || c.Auth.RefreshTokenTTL <= 0 {
+		invalid("auth", "token lifetimes must be positive")
+	}
+	if c.Auth.Password.MinLength < 1 {
+		invalid("auth.password.min_length", "must be at least 1")
+	}
+	if oidc := c.Auth.OIDC; oidc.Enabled() {
+		if !isHTTPURL(oidc.Issuer) {
+			invalid("auth.oidc.issuer", "must be an http or https URL")
+		}
+		if oidc.ClientID == "" {
+			invalid("auth.oidc.client_id", "must be set")
+		}
+		if !isHTTPURL(oidc.RedirectURL) {
+			invalid("auth.oidc.redirect_url", "must be an http or https URL")
+		}
+		validateRoles("auth.oidc", oidc.RoleMapping, oidc.DefaultRole, invalid)
+	}
+	if ldap := c.Auth.LDAP; ldap.Enabled() {
+		if u, err := url.Parse(ldap.URL); err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Host == "" {
+			invalid("auth.ldap.url", "must be an ldap or ldaps URL")
+		} else if ldap.StartTLS && u.Scheme == "ldaps" {
+			invalid("auth.ldap.start_tls", "cannot be used with ldaps")
+		}
+		if ldap.Timeout <= 0 {
+			invalid("auth.ldap.timeout", "must be positive")
+		}
+		if ldap.BaseDN == "" {
+			invalid("auth.ldap.base_dn", "must be set")
+		}
+		if strings.Count(ldap.UserFilter, "%s") != 1 {
+			invalid("auth.ldap.user_filter", "must contain %%s once")
+		}
+		if ldap.GroupBaseDN != "" && strings.Count(ldap.GroupFilter, "%s") != 1 {
+			invalid("auth.ldap.group_filter", "must contain %%s once")
+		}
+		validateRoles("auth.ldap", ldap.RoleMapping, ldap.DefaultRole, invalid)
+	}
+
+	if len(c.CORS.AllowedOrigins) == 0 {
+		invalid("cors.allowed_origins", "must not be empty")
+	}
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+			invalid("cors.allowed_origins", "%q must be * or a scheme and host such as https://example.com", origin)
+		}
+	}
+
+	if c.Workers.PoolSize < 0 {
+		invalid("workers.pool_size", "must not be negative")
+	}
+
+	switch c.Storage.Backend {
+	case "local":
+		if c.Storage.LogsDir == "" || c.Storage.ArtifactsDir == "" {
+			invalid("storage", "logs_dir and artifacts_dir must be set")
+		}
+	case "s3":
+		if c.Storage.S3.Endpoint == "" || c.Storage.S3.Bucket == "" {
+			invalid("storage.s3", "endpoint and bucket must be set")
+		}
+	default:
+		invalid("storage.backend", "must be local or s3")
+	}
+	if c.Storage.WorkspacesDir == "" {
+		invalid("storage.workspaces_dir", "must be set")
+	}
+
+	if c.Executor.Shell == "" {
+		invalid("executor.shell", "must be set")
+	}
+	if c.Executor.ApprovalTTL <= 0 {
+		invalid("executor.approval_ttl", "must be positive")
+	}
+	if c.Executor.MaxLogSize <= 0 {
+		invalid("executor.max_log_size", "must be positive")
+	}
+	if c.Executor.MaxArtifactSize < 0 || c.Executor.MaxTaskArtifacts < 0 {
+		invalid("executor", "artifact limits must not be negative")
+	}
+
+	if c.Notifications.MaxAttempts < 1 {
+		invalid("notifications.max_attempts", "must be at least 1")
+	}
+	if c.Notifications.BaseDelay <= 0 || c.Notifications.MaxDelay < c.Notifications.BaseDelay {
+		invalid("notifications", "base_delay must be positive and at most max_delay")
+	}
+	if _, err := c.Notifications.AllowedWebhookNetworks(); err != nil {
+		invalid("notifications.allowed_networks", "%v", err)
+	}
+
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		invalid("log.level", "must be debug, info, warn or error")
+	}
+	switch c.Log.Format {
+	case "text", "json":
+	default:
+		invalid("log.format", "must be text or json")
+	}
+
+	return errors.Join(errs...)
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// validateRoles checks the roles a section grants to external users.
+func validateRoles(section string, mapping RoleMapping, defaultRole string, invalid func(key, format string, args ...any)) {
+	for group, role := range mapping {
+		if !model.Role(role).Valid() {
+			invalid(section+".role_mapping", "%q maps to unknown role %q", group, role)
+		}
+	}
+	if !model.Role(defaultRole).Valid() {
+		invalid(section+".default_role", "unknown role %q", defaultRole)
+	}
+}
+
+// visit calls fn for every setting with its key.
+func (c *Config) visit(fn func(key string, field reflect.Value, tag reflect.StructTag)) {
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		for i := 0; i < v.NumField(); i++ {
+			sf := v.Type().Field(i)
+			key := prefix + sf.Tag.Get("key")
+			if isSection(sf.Type) {
+				walk(v.Field(i), key+".")
+				continue
+			}
+			fn(key, v.Field(i), sf.Tag)
+		}
+	}
+	walk(reflect.ValueOf(c).Elem(), "")
+}
+
+// apply sets the fields of the section v from the settings of a file.
+func apply(v reflect.Value, prefix string, settings map[string]any) error {
+	fields := make(map[string]int)
+	for i := 0; i < v.NumField(); i++ {
+		fields[v.Type().Field(i).Tag.Get("key")] = i
+	}
+
+	var errs []error
+	for name, value := range settings {
+		key := prefix + name
+		i, ok := fields[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown setting %s", key))
+			continue
+		}
+		field := v.Field(i)
+
+		if isSection(field.Type()) {
+			section, ok := value.(map[string]any)
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s: must be a table of settings", key))
+				continue
+			}
+			if err := apply(field, key+".", section); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		var err error
+		switch value := value.(type) {
+		case nil:
+			err = parse(field, "")
+		case map[string]any:
+			if field.Type() != roleMappingType {
+				err = errors.New("must not be a table")
+				break
+			}
+			mapping := RoleMapping{}
+			for group, role := range value {
+				mapping[group] = fmt.Sprint(role)
+			}
+			field.Set(reflect.ValueOf(mapping))
+		case []any:
+			if field.Type() != reflect.TypeOf([]string(nil)) {
+				err = errors.New("must not be a list")
+				break
+			}
+			list := make([]string, len(value))
+			for i, item := range value {
+				list[i] = fmt.Sprint(item)
+			}
+			field.Set(reflect.ValueOf(list))
+		default:
+			err = parse(field, fmt.Sprint(value))
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func isSection(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateProductionCORS(t *testing.T) {
+	cfg := Default()
+	cfg.Mode = ModeProduction
+	cfg.Auth.JWTSecret = strings.Repeat("s", minProductionSecretLength)
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "cors.allowed_origins") {
+		t.Fatalf("Validate with * in production mode = %v, want a cors.allowed_origins error", err)
+	}
+
+	cfg.CORS.AllowedOrigins = []string{"https://scheduler.example.com"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate with a listed origin: %v", err)
+	}
+}
+
+func TestLoadAuthProviders(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(file, []byte(`
+auth:
+  password:
+    min_length: 12
+  ldap:
+    url: ldaps://ldap.example.com
+    base_dn: dc=example,dc=com
+    role_mapping:
+      cn=admins,ou=groups,dc=example,dc=com: admin
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("OIDC_ISSUER", "https://idp.example.com")
+	t.Setenv("OIDC_CLIENT_ID", "scheduler")
+	t.Setenv("OIDC_REDIRECT_URL", "https://scheduler.example.com/api/auth/oidc/callback")
+	t.Setenv("OIDC_SCOPES", "openid groups")
+	t.Setenv("OIDC_ROLE_MAPPING", "developers=editor, platform-admins=admin")
+
+	cfg, err := Load("test", []string{"-config", file, "-auth.password.require_digit=true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := cfg.Auth.Password; p.MinLength != 12 || !p.RequireDigit {
+		t.Errorf("password policy = %+v", p)
+	}
+	ldap := cfg.Auth.LDAP
+	if !ldap.Enabled() || ldap.RoleMapping["cn=admins,ou=groups,dc=example,dc=com"] != "admin" || ldap.UserFilter != "(uid=%s)" {
+		t.Errorf("ldap = %+v", ldap)
+	}
+	oidc := cfg.Auth.OIDC
+	if !oidc.Enabled() || strings.Join(oidc.Scopes, " ") != "openid groups" {
+		t.Errorf("oidc = %+v", oidc)
+	}
+	if oidc.RoleMapping["developers"] != "editor" || oidc.RoleMapping["platform-admins"] != "admin" {
+		t.Errorf("oidc.role_mapping = %v", oidc.RoleMapping)
+	}
+}
+
+func TestParseRoleMappingWithDNs(t *testing.T) {
+	mapping, err := parseRoleMapping("cn=ops,ou=groups,dc=example,dc=com=operator; cn=admins,ou=groups,dc=example,dc=com=admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mapping) != 2 || mapping["cn=ops,ou=groups,dc=example,dc=com"] != "operator" || mapping["cn=admins,ou=groups,dc=example,dc=com"] != "admin" {
+		t.Errorf("mapping = %v", mapping)
+	}
+	if _, err := parseRoleMapping("developers"); err == nil {
+		t.Error("a group without a role was accepted")
+	}
+}
+
+func TestValidateAuthProviders(t *testing.T) {
+	cfg := Default()
+	cfg.Auth.Password.MinLength = 0
+	cfg.Auth.OIDC.Issuer = "idp.example.com"
+	cfg.Auth.OIDC.DefaultRole = "owner"
+	cfg.Auth.LDAP.URL = "https://ldap.example.com"
+	cfg.Auth.LDAP.UserFilter = "(uid=alice)"
+	cfg.Auth.LDAP.RoleMapping = RoleMapping{"ops": "root"}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate accepted invalid authentication settings")
+	}
+	for _, key := range []string{
+		"auth.password.min_length",
+		"auth.oidc.issuer", "auth.oidc.client_id", "auth.oidc.redirect_url", "auth.oidc.default_role",
+		"auth.ldap.url", "auth.ldap.base_dn", "auth.ldap.user_filter", "auth.ldap.role_mapping",
+	} {
+		if !strings.Contains(err.Error(), key+":") {
+			t.Errorf("no error for %s in:\n%v", key, err)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ByteSize is a size in bytes, written as a number of bytes or with a KiB,
+// MiB or GiB suffix.
+type ByteSize int64
+
+var byteSizeUnits = []struct {
+	suffix string
+	size   ByteSize
+}{
+	{"GiB", 1 << 30},
+	{"MiB", 1 << 20},
+	{"KiB", 1 << 10},
+	{"B", 1},
+}
+
+func parseByteSize(s string) (ByteSize, error) {
+	s = strings.TrimSpace(s)
+	unit := ByteSize(1)
+	for _, u := range byteSizeUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			s, unit = strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), u.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return ByteSize(n) * unit, nil
+}
+
+// RoleMapping maps group names or DNs of an identity provider to roles.
+type RoleMapping map[string]string
+
+// parseRoleMapping parses group=role pairs separated by commas. Group DNs
+// contain commas and equals signs, so pairs may also be separated by
+// semicolons and the role is taken after the last equals sign.
+func parseRoleMapping(s string) (RoleMapping, error) {
+	sep := ","
+	if strings.Contains(s, ";") {
+		sep = ";"
+	}
+	mapping := RoleMapping{}
+	for _, pair := range strings.Split(s, sep) {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		i := strings.LastIndex(pair, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("%q is not a group=role pair", pair)
+		}
+		mapping[strings.TrimSpace(pair[:i])] = strings.TrimSpace(pair[i+1:])
+	}
+	return mapping, nil
+}
+
+var (
+	durationType    = reflect.TypeOf(time.Duration(0))
+	byteSizeType    = reflect.TypeOf(ByteSize(0))
+	roleMappingType = reflect.TypeOf(RoleMapping(nil))
+)
+
+// parse sets a setting from its text. Lists are separated by commas or
+// spaces.
+func parse(field reflect.Value, s string) error {
+	switch field.Type() {
+	case durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	case byteSizeType:
+		b, err := parseByteSize(s)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(b))
+		return nil
+	case roleMappingType:
+		m, err := parseRoleMapping(s)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(m))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		field.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		field.SetInt(int64(n))
+	case reflect.Slice:
+		list := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+		field.Set(reflect.ValueOf(list))
+	default:
+		return fmt.Errorf("unsupported setting type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// APITokenHandler lets users manage their own personal API tokens. Tokens
+// can only be managed from a login session, not with another API token.
+type APITokenHandler struct {
+	authService *service.AuthService
+	audit       *service.AuditService
+}
+
+func NewAPITokenHandler(authService *service.AuthService, audit *service.AuditService) *APITokenHandler {
+	return &APITokenHandler{authService: authService, audit: audit}
+}
+
+func (h *APITokenHandler) ListTokens(ctx context.Context, c *app.RequestContext) {
+	user, ok := h.sessionUser(c)
+	if !ok {
+		return
+	}
+
+	tokens, err := h.authService.ListAPITokens(user.ID)
+	if err != nil {
+		HandleError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (h *APITokenHandler) CreateToken(ctx context.Context, c *app.RequestContext) {
+	user, ok := h.sessionUser(c)
+	if !ok {
+		return
+	}
+
+	var req model.CreateAPITokenRequest
+	if err := c.BindJSON(&req); err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	resp, err := h.authService.CreateAPIToken(user, req)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+	recordAudit(c, h.audit, model.AuditTokenCreate, "token", resp.APIToken.ID, nil, resp.APIToken, nil)
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+func (h *APITokenHandler) RevokeToken(ctx context.Context, c *app.RequestContext) {
+	user, ok := h.sessionUser(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	err = h.authService.RevokeAPIToken(user.ID, id)
+	recordAudit(c, h.audit, model.AuditTokenRevoke, "token", id, nil, nil, err)
+	if err != nil {
+		HandleError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *APITokenHandler) sessionUser(c *app.RequestContext) (*model.User, bool) {
+	if _, ok := currentAPIToken(c); ok {
+		HandleError(c, http.StatusForbidden, errors.New("API tokens cannot manage API tokens"))
+		return nil, false
+	}
+	user, ok := currentUser(c)
+	if !ok {
+		HandleError(c, http.StatusUnauthorized, errors.New("user not found"))
+		return nil, false
+	}
+	return user, true
+}
@@ -3,7 +3,10 @@ package handler
 import (
 	"context"
 	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"gogo-scheduler/internal/model"
 	"gogo-scheduler/internal/service"
@@ -14,11 +17,13 @@ import (
 
 type AuthHandler struct {
 	authService *service.AuthService
+	audit       *service.AuditService
 }
 
-func NewAuthHandler(authService *service.AuthService) *AuthHandler {
+func NewAuthHandler(authService *service.AuthService, audit *service.AuditService) *AuthHandler {
 	return &AuthHandler{
 		authService: authService,
+		audit:       audit,
 	}
 }
 
@@ -45,7 +50,43 @@ func (h *AuthHandler) Login(ctx context.Context, c *app.RequestContext) {
 		return
 	}
 
-	resp, err := h.authService.Login(req.Username, req.Password)
+	resp, err := h.authService.Login(req.Username, req.Password, c.ClientIP())
+	// logins with two-factor authentication are recorded once completed
+	if err != nil || !resp.TwoFactorRequired {
+		var user *model.User
+		if err == nil {
+			user = resp.User
+		}
+		recordLogin(c, h.audit, req.Username, user, err)
+	}
+	if err != nil {
+		loginError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// loginError responds to a failed login, telling throttled clients when to
+// try again.
+func loginError(c *app.RequestContext, err error) {
+	var throttled *service.LoginThrottledError
+	if errors.As(err, &throttled) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+}
+
+func (h *AuthHandler) Refresh(ctx context.Context, c *app.RequestContext) {
+	var req model.RefreshRequest
+	if err := c.BindJSON(&req); err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	resp, err := h.authService.Refresh(req.RefreshToken)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
@@ -54,6 +95,33 @@ func (h *AuthHandler) Login(ctx context.Context, c *app.RequestContext) {
 	c.JSON(http.StatusOK, resp)
 }
 
+// Logout revokes the access token of the request and, if given, the
+// session of the refresh token.
+func (h *AuthHandler) Logout(ctx context.Context, c *app.RequestContext) {
+	var req model.LogoutRequest
+	if len(c.Request.Body()) > 0 {
+		if err := c.BindJSON(&req); err != nil {
+			HandleError(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	accessToken := strings.TrimPrefix(string(c.GetHeader("Authorization")), "Bearer ")
+	if strings.HasPrefix(accessToken, model.APITokenPrefix) {
+		HandleError(c, http.StatusBadRequest, errors.New("API tokens are revoked with DELETE /api/tokens/:id"))
+		return
+	}
+
+	err := h.authService.Logout(accessToken, req.RefreshToken)
+	recordAudit(c, h.audit, model.AuditLogout, "user", nil, nil, nil, err)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]string{"message": "logged out"})
+}
+
 func (h *AuthHandler) ChangePassword(ctx context.Context, c *app.RequestContext) {
 	AuthMiddleware(h.authService)(ctx, c)
 	var req struct {
@@ -74,6 +142,7 @@ func (h *AuthHandler) ChangePassword(ctx context.Context, c *app.RequestContext)
 	}
 
 	err := h.authService.ChangePassword(user.(*model.User).Username, req.OldPassword, req.NewPassword)
+	recordAudit(c, h.audit, model.AuditPasswordChange, "user", user.(*model.User).ID, nil, nil, err)
 	if err != nil {
 		HandleError(c, http.StatusBadRequest, err)
 		return
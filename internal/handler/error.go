@@ -1,14 +1,44 @@
 package handler
 
-import "github.com/cloudwego/hertz/pkg/app"
+import (
+	"errors"
+	"net/http"
+
+	"gogo-scheduler/internal/service"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
 
 type ErrorResponse struct {
 	Message string `json:"message"`
 }
 
+// HandleError responds with the error message. The error is also logged
+// with the request.
 func HandleError(c *app.RequestContext, code int, err error) {
+	c.Error(err)
 	response := ErrorResponse{
 		Message: err.Error(),
 	}
 	c.JSON(code, response)
 }
+
+// HandleScriptError reports an error from ScriptService, distinguishing
+// missing or unreadable resources from missing permissions, invalid
+// requests and exhausted run quotas.
+func HandleScriptError(c *app.RequestContext, err error) {
+	switch {
+	case errors.Is(err, service.ErrScriptNotFound), errors.Is(err, service.ErrTaskNotFound):
+		HandleError(c, http.StatusNotFound, err)
+	case errors.Is(err, service.ErrAccessDenied), errors.Is(err, service.ErrSelfApproval):
+		HandleError(c, http.StatusForbidden, err)
+	case errors.Is(err, service.ErrNotAwaitingApproval), errors.Is(err, service.ErrApprovalExpired):
+		HandleError(c, http.StatusConflict, err)
+	case errors.Is(err, service.ErrApproverNotFound):
+		HandleError(c, http.StatusBadRequest, err)
+	case errors.Is(err, service.ErrRunQuotaExceeded):
+		HandleError(c, http.StatusTooManyRequests, err)
+	default:
+		HandleError(c, http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/service"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/gin-gonic/gin"
+)
+
+type GroupHandler struct {
+	service *service.GroupService
+	audit   *service.AuditService
+}
+
+func NewGroupHandler(service *service.GroupService, audit *service.AuditService) *GroupHandler {
+	return &GroupHandler{service: service, audit: audit}
+}
+
+func (h *GroupHandler) ListGroups(ctx context.Context, c *app.RequestContext) {
+	groups, err := h.service.ListGroups()
+	if err != nil {
+		HandleError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, groups)
+}
+
+func (h *GroupHandler) CreateGroup(ctx context.Context, c *app.RequestContext) {
+	var req model.GroupRequest
+	if err := c.BindJSON(&req); err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	group, err := h.service.CreateGroup(req)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+	recordAudit(c, h.audit, model.AuditGroupCreate, "group", group.ID, nil, group, nil)
+
+	c.JSON(http.StatusCreated, group)
+}
+
+func (h *GroupHandler) DeleteGroup(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	before, _ := h.service.GetGroup(id)
+	err = h.service.DeleteGroup(id)
+	recordAudit(c, h.audit, model.AuditGroupDelete, "group", id, before, nil, err)
+	if err != nil {
+		HandleError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *GroupHandler) AddMember(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+	var req model.GroupMemberRequest
+	if err := c.BindJSON(&req); err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	group, err := h.service.AddMember(id, req.UserID)
+	recordAudit(c, h.audit, model.AuditGroupMemberAdd, "group", id, nil, gin.H{"user_id": req.UserID}, err)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+func (h *GroupHandler) RemoveMember(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+	userID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	group, err := h.service.RemoveMember(id, userID)
+	recordAudit(c, h.audit, model.AuditGroupMemberRemove, "group", id, gin.H{"user_id": userID}, nil, err)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/service"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	service *service.HealthService
+}
+
+func NewHealthHandler(service *service.HealthService) *HealthHandler {
+	return &HealthHandler{service: service}
+}
+
+// Live reports that the process is up. It checks nothing else, so that an
+// orchestrator does not restart the scheduler for a failing dependency.
+func (h *HealthHandler) Live(ctx context.Context, c *app.RequestContext) {
+	c.JSON(http.StatusOK, gin.H{"status": model.HealthOK})
+}
+
+// Ready reports whether the scheduler can serve requests and run scripts,
+// with 503 Service Unavailable if any component is failing.
+func (h *HealthHandler) Ready(ctx context.Context, c *app.RequestContext) {
+	report := h.service.Check(ctx)
+	code := http.StatusOK
+	if report.Status != model.HealthOK {
+		code = http.StatusServiceUnavailable
+	}
+	c.JSON(code, report)
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"gogo-scheduler/internal/logging"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	requestIDKey    = "request_id"
+	// maxRequestIDLength limits request IDs taken from the caller
+	maxRequestIDLength = 128
+)
+
+// probeRoutes are polled by monitoring and logged at debug level only.
+var probeRoutes = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+// LoggingMiddleware logs every request once it is handled. Requests get
+// the ID of the X-Request-ID header, or a new one, which is returned in the
+// response and added to everything logged with the request's context.
+func LoggingMiddleware() app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		id := string(ctx.GetHeader(RequestIDHeader))
+		if id == "" || len(id) > maxRequestIDLength {
+			id = newRequestID()
+		}
+		ctx.Set(requestIDKey, id)
+		ctx.Response.Header.Set(RequestIDHeader, id)
+		c = logging.With(c, requestIDKey, id)
+
+		start := time.Now()
+		ctx.Next(c)
+
+		route := ctx.FullPath()
+		status := ctx.Response.StatusCode()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case probeRoutes[route]:
+			level = slog.LevelDebug
+		}
+		args := []any{
+			"method", string(ctx.Method()),
+			"path", string(ctx.Request.URI().Path()),
+			"route", route,
+			"status", status,
+			"duration_ms", time.Since(start).Milliseconds(),
+			"client_ip", ctx.ClientIP(),
+		}
+		if err := ctx.Errors.Last(); err != nil {
+			args = append(args, "error", err.Err)
+		}
+		slog.Log(c, level, "request", args...)
+	}
+}
+
+// logContext returns a context carrying the request ID, for helpers that
+// only get the request.
+func logContext(c *app.RequestContext) context.Context {
+	return logging.With(context.Background(), requestIDKey, c.GetString(requestIDKey))
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gogo-scheduler/internal/metrics"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/adaptor"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsMiddleware counts requests and measures their latency by route.
+// Requests that match no route, such as those for the frontend, are
+// recorded with the route "unmatched".
+func MetricsMiddleware() app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		start := time.Now()
+		ctx.Next(c)
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := string(ctx.Method())
+		code := strconv.Itoa(ctx.Response.StatusCode())
+		metrics.HTTPRequests.WithLabelValues(method, route, code).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Metrics serves the metrics in the Prometheus text format.
+func Metrics() app.HandlerFunc {
+	handler := promhttp.Handler()
+	return func(c context.Context, ctx *app.RequestContext) {
+		req, err := adaptor.GetCompatRequest(&ctx.Request)
+		if err != nil {
+			HandleError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		handler.ServeHTTP(adaptor.GetCompatResponseWriter(&ctx.Response), req.WithContext(c))
+	}
+}
@@ -2,14 +2,25 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 
+	"gogo-scheduler/internal/model"
 	"gogo-scheduler/internal/service"
 
 	"github.com/cloudwego/hertz/pkg/app"
 )
 
+// accountSetupRoutes are the only routes open to a user who must change
+// their password or set up two-factor authentication.
+var accountSetupRoutes = map[string]bool{
+	"/api/auth/change-password": true,
+	"/api/auth/logout":          true,
+	"/api/auth/2fa/enroll":      true,
+	"/api/auth/2fa/activate":    true,
+}
+
 func AuthMiddleware(authService *service.AuthService) app.HandlerFunc {
 	return func(c context.Context, ctx *app.RequestContext) {
 		authHeaderStr := ctx.GetHeader("Authorization")
@@ -27,14 +38,175 @@ func AuthMiddleware(authService *service.AuthService) app.HandlerFunc {
 			return
 		}
 
+		// Personal API tokens are accepted alongside JWTs
+		if strings.HasPrefix(parts[1], model.APITokenPrefix) {
+			user, token, err := authService.ValidateAPIToken(parts[1])
+			if err != nil {
+				ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+				ctx.Abort()
+				return
+			}
+			if !checkAccountSetup(ctx, authService, user) {
+				return
+			}
+			ctx.Set("user", user)
+			ctx.Set("api_token", token)
+			ctx.Next(c)
+			return
+		}
+
 		user, err := authService.ValidateToken(parts[1])
 		if err != nil {
 			ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid token"})
 			ctx.Abort()
 			return
 		}
+		if !checkAccountSetup(ctx, authService, user) {
+			return
+		}
 
 		ctx.Set("user", user)
 		ctx.Next(c)
 	}
 }
+
+// checkAccountSetup aborts the request if the user must change their
+// password or set up two-factor authentication first.
+func checkAccountSetup(ctx *app.RequestContext, authService *service.AuthService, user *model.User) bool {
+	if accountSetupRoutes[ctx.FullPath()] {
+		return true
+	}
+	if user.MustChangePassword {
+		ctx.JSON(http.StatusForbidden, map[string]string{"error": "password change required"})
+		ctx.Abort()
+		return false
+	}
+	if authService.TwoFactorSetupRequired(user) {
+		ctx.JSON(http.StatusForbidden, map[string]string{"error": "two-factor authentication setup required"})
+		ctx.Abort()
+		return false
+	}
+	return true
+}
+
+// RequirePermission rejects requests from users whose role lacks the
+// permission. It must run after AuthMiddleware.
+func RequirePermission(permission model.Permission) app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		user, ok := currentUser(ctx)
+		if !ok {
+			ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "authentication required"})
+			ctx.Abort()
+			return
+		}
+
+		if !user.Role.Can(permission) {
+			ctx.JSON(http.StatusForbidden, map[string]string{"error": "permission denied"})
+			ctx.Abort()
+			return
+		}
+
+		if token, ok := currentAPIToken(ctx); ok && !token.HasScope(permission) {
+			ctx.JSON(http.StatusForbidden, map[string]string{"error": "token lacks the " + string(permission) + " scope"})
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next(c)
+	}
+}
+
+// ProjectMiddleware resolves the project of the :project route parameter,
+// or the default project on routes without one. It replaces the user in
+// the context with a copy holding the user's role in that project, so that
+// RequirePermission checks the project role. It must run after
+// AuthMiddleware.
+func ProjectMiddleware(projectService *service.ProjectService) app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		user, ok := currentUser(ctx)
+		if !ok {
+			ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "authentication required"})
+			ctx.Abort()
+			return
+		}
+
+		slug := ctx.Param("project")
+		if slug == "" {
+			slug = model.DefaultProjectSlug
+		}
+		project, err := projectService.Resolve(slug, user)
+		if errors.Is(err, service.ErrProjectNotFound) {
+			ctx.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+			ctx.Abort()
+			return
+		}
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			ctx.Abort()
+			return
+		}
+
+		projectUser := *user
+		projectUser.Role = project.Role
+		ctx.Set("user", &projectUser)
+		ctx.Set("project", project)
+		ctx.Next(c)
+	}
+}
+
+// currentUser returns the user set by AuthMiddleware.
+func currentUser(ctx *app.RequestContext) (*model.User, bool) {
+	value, exists := ctx.Get("user")
+	if !exists {
+		return nil, false
+	}
+	user, ok := value.(*model.User)
+	return user, ok
+}
+
+// requestUser returns the authenticated user, responding with 401 if there
+// is none.
+func requestUser(c *app.RequestContext) (*model.User, bool) {
+	user, ok := currentUser(c)
+	if !ok {
+		HandleError(c, http.StatusUnauthorized, errors.New("user not found"))
+		return nil, false
+	}
+	return user, true
+}
+
+// currentProject returns the project set by ProjectMiddleware.
+func currentProject(ctx *app.RequestContext) (*model.Project, bool) {
+	value, exists := ctx.Get("project")
+	if !exists {
+		return nil, false
+	}
+	project, ok := value.(*model.Project)
+	return project, ok
+}
+
+// requestScope returns the user and project of a request that went through
+// ProjectMiddleware, responding with an error if either is missing.
+func requestScope(c *app.RequestContext) (model.ProjectScope, bool) {
+	user, ok := requestUser(c)
+	if !ok {
+		return model.ProjectScope{}, false
+	}
+	project, ok := currentProject(c)
+	if !ok {
+		HandleError(c, http.StatusInternalServerError, errors.New("project not resolved"))
+		return model.ProjectScope{}, false
+	}
+	return model.ProjectScope{ProjectID: project.ID, User: user}, true
+}
+
+// currentAPIToken returns the API token the request was authenticated with,
+// if it did not use a JWT.
+func currentAPIToken(ctx *app.RequestContext) (*model.APIToken, bool) {
+	value, exists := ctx.Get("api_token")
+	if !exists {
+		return nil, false
+	}
+	token, ok := value.(*model.APIToken)
+	return token, ok
+}
@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/service"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationHandler struct {
+	service *service.NotificationService
+	audit   *service.AuditService
+}
+
+func NewNotificationHandler(service *service.NotificationService, audit *service.AuditService) *NotificationHandler {
+	return &NotificationHandler{service: service, audit: audit}
+}
+
+func (h *NotificationHandler) ListChannels(ctx context.Context, c *app.RequestContext) {
+	scope, ok := requestScope(c)
+	if !ok {
+		return
+	}
+
+	channels, err := h.service.ListChannels(scope.ProjectID)
+	if err != nil {
+		HandleError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, channels)
+}
+
+func (h *NotificationHandler) GetChannel(ctx context.Context, c *app.RequestContext) {
+	scope, id, ok := h.channelParams(c)
+	if !ok {
+		return
+	}
+
+	channel, err := h.service.GetChannel(scope.ProjectID, id)
+	if err != nil {
+		handleChannelError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, channel)
+}
+
+func (h *NotificationHandler) CreateChannel(ctx context.Context, c *app.RequestContext) {
+	var req model.NotificationChannelRequest
+	if err := c.BindJSON(&req); err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+	scope, ok := requestScope(c)
+	if !ok {
+		return
+	}
+
+	channel, err := h.service.CreateChannel(scope.ProjectID, req)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+	recordAudit(c, h.audit, model.AuditChannelCreate, "channel", channel.ID, nil, channel, nil)
+
+	c.JSON(http.StatusCreated, channel)
+}
+
+func (h *NotificationHandler) UpdateChannel(ctx context.Context, c *app.RequestContext) {
+	scope, id, ok := h.channelParams(c)
+	if !ok {
+		return
+	}
+	var req model.NotificationChannelRequest
+	if err := c.BindJSON(&req); err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	before, _ := h.service.GetChannel(scope.ProjectID, id)
+	channel, err := h.service.UpdateChannel(scope.ProjectID, id, req)
+	recordAudit(c, h.audit, model.AuditChannelUpdate, "channel", id, before, channel, err)
+	if err != nil {
+		handleChannelError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, channel)
+}
+
+func (h *NotificationHandler) DeleteChannel(ctx context.Context, c *app.RequestContext) {
+	scope, id, ok := h.channelParams(c)
+	if !ok {
+		return
+	}
+
+	before, _ := h.service.GetChannel(scope.ProjectID, id)
+	err := h.service.DeleteChannel(scope.ProjectID, id)
+	recordAudit(c, h.audit, model.AuditChannelDelete, "channel", id, before, nil, err)
+	if err != nil {
+		handleChannelError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// TestChannel sends a test notification and returns its delivery.
+func (h *NotificationHandler) TestChannel(ctx context.Context, c *app.RequestContext) {
+	scope, id, ok := h.channelParams(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.service.TestChannel(scope.ProjectID, id)
+	if err != nil {
+		handleChannelError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+func (h *NotificationHandler) ListDeliveries(ctx context.Context, c *app.RequestContext) {
+	scope, id, ok := h.channelParams(c)
+	if !ok {
+		return
+	}
+
+	page, pageSize := parsePage(c)
+	deliveries, err := h.service.ListDeliveries(scope.ProjectID, id, page, pageSize)
+	if err != nil {
+		handleChannelError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+func (h *NotificationHandler) channelParams(c *app.RequestContext) (model.ProjectScope, int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return model.ProjectScope{}, 0, false
+	}
+	scope, ok := requestScope(c)
+	return scope, id, ok
+}
+
+// handleChannelError reports missing channels as not found and anything
+// else as an invalid request.
+func handleChannelError(c *app.RequestContext, err error) {
+	if errors.Is(err, service.ErrChannelNotFound) {
+		HandleError(c, http.StatusNotFound, err)
+		return
+	}
+	HandleError(c, http.StatusBadRequest, err)
+}
+
+func (h *ScriptHandler) GetNotifications(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+	scope, ok := requestScope(c)
+	if !ok {
+		return
+	}
+
+	notifications, err := h.service.ListNotifications(ctx, scope, id)
+	if err != nil {
+		HandleScriptError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, notifications)
+}
+
+// SetNotifications replaces the channels a script notifies with the list
+// in the body.
+func (h *ScriptHandler) SetNotifications(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+	var req []model.ScriptNotificationRequest
+	if err := c.BindJSON(&req); err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+	scope, ok := requestScope(c)
+	if !ok {
+		return
+	}
+
+	before, _ := h.service.ListNotifications(ctx, scope, id)
+	notifications, err := h.service.SetNotifications(ctx, scope, id, req)
+	recordAudit(c, h.audit, model.AuditScriptNotifications, "script", id, gin.H{"notifications": before}, gin.H{"notifications": notifications}, err)
+	if err != nil {
+		handleShareError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, notifications)
+}
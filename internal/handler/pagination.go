@@ -0,0 +1,17 @@
+package handler
+
+import (
+	"strconv"
+
+	"gogo-scheduler/internal/model"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// parsePage reads the page and page_size query parameters, falling back to
+// the first page of model.DefaultPageSize items.
+func parsePage(c *app.RequestContext) (int, int) {
+	page, _ := strconv.Atoi(c.Query("page"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+	return model.NormalizePage(page, pageSize)
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/service"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/gin-gonic/gin"
+)
+
+type ProjectHandler struct {
+	service *service.ProjectService
+	audit   *service.AuditService
+}
+
+func NewProjectHandler(service *service.ProjectService, audit *service.AuditService) *ProjectHandler {
+	return &ProjectHandler{service: service, audit: audit}
+}
+
+func (h *ProjectHandler) ListProjects(ctx context.Context, c *app.RequestContext) {
+	user, ok := requestUser(c)
+	if !ok {
+		return
+	}
+
+	projects, err := h.service.ListProjects(user)
+	if err != nil {
+		HandleError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, projects)
+}
+
+func (h *ProjectHandler) CreateProject(ctx context.Context, c *app.RequestContext) {
+	var req model.CreateProjectRequest
+	if err := c.BindJSON(&req); err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	project, err := h.service.CreateProject(req)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+	recordAudit(c, h.audit, model.AuditProjectCreate, "project", project.ID, nil, project, nil)
+
+	c.JSON(http.StatusCreated, project)
+}
+
+func (h *ProjectHandler) GetProject(ctx context.Context, c *app.RequestContext) {
+	project, ok := h.project(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, project)
+}
+
+func (h *ProjectHandler) UpdateProject(ctx context.Context, c *app.RequestContext) {
+	project, ok := h.project(c)
+	if !ok {
+		return
+	}
+	user, ok := requestUser(c)
+	if !ok {
+		return
+	}
+	var req model.UpdateProjectRequest
+	if err := c.BindJSON(&req); err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	updated, err := h.service.UpdateProject(project, user.ID, req)
+	recordAudit(c, h.audit, model.AuditProjectUpdate, "project", project.ID, project, updated, err)
+	if errors.Is(err, service.ErrQuotaChangeDenied) {
+		HandleError(c, http.StatusForbidden, err)
+		return
+	}
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+func (h *ProjectHandler) DeleteProject(ctx context.Context, c *app.RequestContext) {
+	project, ok := h.project(c)
+	if !ok {
+		return
+	}
+
+	err := h.service.DeleteProject(project)
+	recordAudit(c, h.audit, model.AuditProjectDelete, "project", project.ID, project, nil, err)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *ProjectHandler) ListMembers(ctx context.Context, c *app.RequestContext) {
+	project, ok := h.project(c)
+	if !ok {
+		return
+	}
+
+	members, err := h.service.ListMembers(project.ID)
+	if err != nil {
+		HandleError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+func (h *ProjectHandler) SetMember(ctx context.Context, c *app.RequestContext) {
+	project, ok := h.project(c)
+	if !ok {
+		return
+	}
+	userID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+	var req model.ProjectMemberRequest
+	if err := c.BindJSON(&req); err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	member, err := h.service.SetMember(project.ID, userID, req.Role)
+	recordAudit(c, h.audit, model.AuditProjectMemberSet, "project", project.ID, nil, gin.H{"user_id": userID, "role": req.Role}, err)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+func (h *ProjectHandler) RemoveMember(ctx context.Context, c *app.RequestContext) {
+	project, ok := h.project(c)
+	if !ok {
+		return
+	}
+	userID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	err = h.service.RemoveMember(project.ID, userID)
+	recordAudit(c, h.audit, model.AuditProjectMemberRemove, "project", project.ID, gin.H{"user_id": userID}, nil, err)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *ProjectHandler) project(c *app.RequestContext) (*model.Project, bool) {
+	project, ok := currentProject(c)
+	if !ok {
+		HandleError(c, http.StatusInternalServerError, errors.New("project not resolved"))
+		return nil, false
+	}
+	return project, true
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+type RetentionHandler struct {
+	service *service.RetentionService
+	audit   *service.AuditService
+}
+
+func NewRetentionHandler(service *service.RetentionService, audit *service.AuditService) *RetentionHandler {
+	return &RetentionHandler{service: service, audit: audit}
+}
+
+func (h *RetentionHandler) GetGlobalPolicy(ctx context.Context, c *app.RequestContext) {
+	h.getPolicy(c, 0)
+}
+
+func (h *RetentionHandler) UpdateGlobalPolicy(ctx context.Context, c *app.RequestContext) {
+	h.updatePolicy(c, 0)
+}
+
+func (h *RetentionHandler) GetScriptPolicy(ctx context.Context, c *app.RequestContext) {
+	id, ok := h.authorizeScript(ctx, c, model.PermissionRead)
+	if !ok {
+		return
+	}
+	h.getPolicy(c, id)
+}
+
+func (h *RetentionHandler) UpdateScriptPolicy(ctx context.Context, c *app.RequestContext) {
+	id, ok := h.authorizeScript(ctx, c, model.PermissionEdit)
+	if !ok {
+		return
+	}
+	h.updatePolicy(c, id)
+}
+
+func (h *RetentionHandler) DeleteScriptPolicy(ctx context.Context, c *app.RequestContext) {
+	id, ok := h.authorizeScript(ctx, c, model.PermissionEdit)
+	if !ok {
+		return
+	}
+
+	before, _ := h.service.GetPolicy(id)
+	err := h.service.DeletePolicy(id)
+	recordAudit(c, h.audit, model.AuditRetentionDelete, "retention_policy", id, before, nil, err)
+	if err != nil {
+		HandleError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// authorizeScript returns the ID of the :id script, responding with an
+// error unless the user has at least the given level on it in the
+// request's project.
+func (h *RetentionHandler) authorizeScript(ctx context.Context, c *app.RequestContext, level model.Permission) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return 0, false
+	}
+	scope, ok := requestScope(c)
+	if !ok {
+		return 0, false
+	}
+	if err := h.service.AuthorizeScript(ctx, scope, id, level); err != nil {
+		HandleScriptError(c, err)
+		return 0, false
+	}
+	return id, true
+}
+
+// DryRun reports what the retention job would remove from the request's
+// project without removing it.
+func (h *RetentionHandler) DryRun(ctx context.Context, c *app.RequestContext) {
+	h.run(c, true)
+}
+
+// Run applies the retention policies to the request's project immediately.
+func (h *RetentionHandler) Run(ctx context.Context, c *app.RequestContext) {
+	h.run(c, false)
+}
+
+func (h *RetentionHandler) getPolicy(c *app.RequestContext, scriptID int64) {
+	policy, err := h.service.GetPolicy(scriptID)
+	if err != nil {
+		HandleError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if policy == nil {
+		HandleError(c, http.StatusNotFound, errors.New("no retention policy for script"))
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+func (h *RetentionHandler) updatePolicy(c *app.RequestContext, scriptID int64) {
+	var req model.RetentionPolicyRequest
+	if err := c.BindJSON(&req); err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.KeepLastN < 0 || req.KeepDays < 0 || req.KeepFailedDays < 0 {
+		HandleError(c, http.StatusBadRequest, errors.New("retention values must not be negative"))
+		return
+	}
+
+	before, _ := h.service.GetPolicy(scriptID)
+	policy, err := h.service.UpdatePolicy(scriptID, req)
+	recordAudit(c, h.audit, model.AuditRetentionUpdate, "retention_policy", scriptID, before, policy, err)
+	if err != nil {
+		HandleError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+func (h *RetentionHandler) run(c *app.RequestContext, dryRun bool) {
+	scope, ok := requestScope(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.service.Run(scope.ProjectID, dryRun)
+	if !dryRun {
+		recordAudit(c, h.audit, model.AuditRetentionRun, "retention_policy", nil, nil, report, err)
+	}
+	if err != nil {
+		HandleError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -2,6 +2,8 @@ package handler
 
 import (
 	"context"
+	"errors"
+	"gogo-scheduler/internal/model"
 	"gogo-scheduler/internal/service"
 	"net/http"
 	"strconv"
@@ -12,29 +14,39 @@ import (
 
 type ScriptHandler struct {
 	service *service.ScriptService
+	audit   *service.AuditService
 }
 
-func NewScriptHandler(service *service.ScriptService) *ScriptHandler {
-	return &ScriptHandler{service: service}
+func NewScriptHandler(service *service.ScriptService, audit *service.AuditService) *ScriptHandler {
+	return &ScriptHandler{service: service, audit: audit}
 }
 
 func (h *ScriptHandler) CreateScript(ctx context.Context, c *app.RequestContext) {
-	var script struct {
-		Name    string `json:"name" binding:"required"`
-		Type    string `json:"type" binding:"required"`
-		Content string `json:"content" binding:"required"`
+	var req model.ScriptRequest
+	if err := c.BindJSON(&req); err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
 	}
-
-	if err := c.BindJSON(&script); err != nil {
+	if err := service.ValidatePatterns(req.Artifacts); err != nil {
 		HandleError(c, http.StatusBadRequest, err)
 		return
 	}
+	if req.Timeout < 0 {
+		HandleError(c, http.StatusBadRequest, errors.New("timeout must not be negative"))
+		return
+	}
 
-	result, err := h.service.CreateScript(script.Name, script.Type, script.Content)
+	scope, ok := requestScope(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.CreateScript(ctx, scope, req)
 	if err != nil {
-		HandleError(c, http.StatusInternalServerError, err)
+		HandleScriptError(c, err)
 		return
 	}
+	recordAudit(c, h.audit, model.AuditScriptCreate, "script", result.ID, nil, result, nil)
 
 	c.JSON(http.StatusCreated, result)
 }
@@ -46,7 +58,21 @@ func (h *ScriptHandler) RunScript(ctx context.Context, c *app.RequestContext) {
 		return
 	}
 
-	output, err := h.service.RunScriptAsync(id)
+	scope, ok := requestScope(c)
+	if !ok {
+		return
+	}
+
+	task, err := h.service.RunScriptAsync(ctx, scope, id)
+	var output int64
+	if task != nil {
+		output = task.ID
+	}
+	recordAudit(c, h.audit, model.AuditScriptRun, "script", id, nil, gin.H{"task_id": output}, err)
+	if err != nil && task == nil {
+		HandleScriptError(c, err)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":  err.Error(),
@@ -56,9 +82,13 @@ func (h *ScriptHandler) RunScript(ctx context.Context, c *app.RequestContext) {
 		return
 	}
 
+	status := "success"
+	if task.Status == "awaiting_approval" {
+		status = task.Status
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"output": output,
-		"status": "success",
+		"status": status,
 	})
 }
 
@@ -69,9 +99,14 @@ func (h *ScriptHandler) GetScript(ctx context.Context, c *app.RequestContext) {
 		return
 	}
 
-	script, err := h.service.GetScript(id)
+	scope, ok := requestScope(c)
+	if !ok {
+		return
+	}
+
+	script, err := h.service.GetScript(ctx, scope, id)
 	if err != nil {
-		HandleError(c, http.StatusNotFound, err)
+		HandleScriptError(c, err)
 		return
 	}
 
@@ -79,7 +114,21 @@ func (h *ScriptHandler) GetScript(ctx context.Context, c *app.RequestContext) {
 }
 
 func (h *ScriptHandler) ListScripts(ctx context.Context, c *app.RequestContext) {
-	scripts, err := h.service.ListScripts()
+	scope, ok := requestScope(c)
+	if !ok {
+		return
+	}
+
+	page, pageSize := parsePage(c)
+	scripts, err := h.service.ListScripts(ctx, scope, model.ScriptQuery{
+		Page:     page,
+		PageSize: pageSize,
+		Search:   c.Query("q"),
+		Type:     c.Query("type"),
+		Tag:      c.Query("tag"),
+		Sort:     c.Query("sort"),
+		Order:    c.Query("order"),
+	})
 	if err != nil {
 		HandleError(c, http.StatusInternalServerError, err)
 		return
@@ -95,8 +144,16 @@ func (h *ScriptHandler) DeleteScript(ctx context.Context, c *app.RequestContext)
 		return
 	}
 
-	if err := h.service.DeleteScript(id); err != nil {
-		HandleError(c, http.StatusInternalServerError, err)
+	scope, ok := requestScope(c)
+	if !ok {
+		return
+	}
+
+	before, _ := h.service.GetScript(ctx, scope, id)
+	err = h.service.DeleteScript(ctx, scope, id)
+	recordAudit(c, h.audit, model.AuditScriptDelete, "script", id, before, nil, err)
+	if err != nil {
+		HandleScriptError(c, err)
 		return
 	}
 
@@ -110,8 +167,13 @@ func (h *ScriptHandler) DeleteTask(ctx context.Context, c *app.RequestContext) {
 		return
 	}
 
-	if err := h.service.DeleteTask(id); err != nil {
-		HandleError(c, http.StatusInternalServerError, err)
+	scope, ok := requestScope(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteTask(ctx, scope, id); err != nil {
+		HandleScriptError(c, err)
 		return
 	}
 
@@ -125,20 +187,30 @@ func (h *ScriptHandler) UpdateScript(ctx context.Context, c *app.RequestContext)
 		return
 	}
 
-	var script struct {
-		Name    string `json:"name" binding:"required"`
-		Type    string `json:"type" binding:"required"`
-		Content string `json:"content" binding:"required"`
+	var req model.ScriptRequest
+	if err := c.BindJSON(&req); err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
 	}
-
-	if err := c.BindJSON(&script); err != nil {
+	if err := service.ValidatePatterns(req.Artifacts); err != nil {
 		HandleError(c, http.StatusBadRequest, err)
 		return
 	}
+	if req.Timeout < 0 {
+		HandleError(c, http.StatusBadRequest, errors.New("timeout must not be negative"))
+		return
+	}
 
-	result, err := h.service.UpdateScript(id, script.Name, script.Type, script.Content)
+	scope, ok := requestScope(c)
+	if !ok {
+		return
+	}
+
+	before, _ := h.service.GetScript(ctx, scope, id)
+	result, err := h.service.UpdateScript(ctx, scope, id, req)
+	recordAudit(c, h.audit, model.AuditScriptUpdate, "script", id, before, result, err)
 	if err != nil {
-		HandleError(c, http.StatusInternalServerError, err)
+		HandleScriptError(c, err)
 		return
 	}
 
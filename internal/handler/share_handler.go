@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/service"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *ScriptHandler) ListShares(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+	scope, ok := requestScope(c)
+	if !ok {
+		return
+	}
+
+	shares, err := h.service.ListShares(ctx, scope, id)
+	if err != nil {
+		HandleScriptError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, shares)
+}
+
+func (h *ScriptHandler) ShareScript(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+	var req model.ShareRequest
+	if err := c.BindJSON(&req); err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+	scope, ok := requestScope(c)
+	if !ok {
+		return
+	}
+
+	share, err := h.service.ShareScript(ctx, scope, id, req)
+	recordAudit(c, h.audit, model.AuditScriptShare, "script", id, nil, share, err)
+	if err != nil {
+		handleShareError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, share)
+}
+
+func (h *ScriptHandler) UnshareScript(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+	shareID, err := strconv.ParseInt(c.Param("share_id"), 10, 64)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+	scope, ok := requestScope(c)
+	if !ok {
+		return
+	}
+
+	err = h.service.UnshareScript(ctx, scope, id, shareID)
+	recordAudit(c, h.audit, model.AuditScriptUnshare, "script", id, nil, gin.H{"share_id": shareID}, err)
+	if err != nil {
+		handleShareError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *ScriptHandler) TransferOwnership(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+	var req model.TransferOwnershipRequest
+	if err := c.BindJSON(&req); err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+	scope, ok := requestScope(c)
+	if !ok {
+		return
+	}
+
+	before, _ := h.service.GetScript(ctx, scope, id)
+	script, err := h.service.TransferOwnership(ctx, scope, id, req.OwnerID)
+	recordAudit(c, h.audit, model.AuditScriptTransfer, "script", id, before, script, err)
+	if err != nil {
+		handleShareError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, script)
+}
+
+// handleShareError reports access errors like HandleScriptError and
+// anything else as an invalid request.
+func handleShareError(c *app.RequestContext, err error) {
+	if errors.Is(err, service.ErrScriptNotFound) || errors.Is(err, service.ErrAccessDenied) {
+		HandleScriptError(c, err)
+		return
+	}
+	HandleError(c, http.StatusBadRequest, err)
+}
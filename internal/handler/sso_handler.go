@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"gogo-scheduler/internal/service"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol"
+)
+
+// ssoStateCookie holds the state of the login started in the browser. It is
+// only sent to the callback.
+const (
+	ssoStateCookie     = "gogo_oidc_state"
+	ssoStateCookiePath = "/api/auth/oidc/"
+)
+
+var errSSOStateMismatch = errors.New("login was started in another browser, please try again")
+
+type SSOHandler struct {
+	service *service.SSOService
+	audit   *service.AuditService
+	// redirectURL is where the browser is sent after login. The tokens are
+	// passed in the URL fragment so they never reach server logs.
+	redirectURL string
+}
+
+func NewSSOHandler(service *service.SSOService, audit *service.AuditService, redirectURL string) *SSOHandler {
+	if redirectURL == "" {
+		redirectURL = "/"
+	}
+	return &SSOHandler{service: service, audit: audit, redirectURL: redirectURL}
+}
+
+// Login redirects the browser to the identity provider.
+func (h *SSOHandler) Login(ctx context.Context, c *app.RequestContext) {
+	authURL, state, err := h.service.Begin(ctx)
+	if errors.Is(err, service.ErrTooManyLogins) {
+		HandleError(c, http.StatusServiceUnavailable, err)
+		return
+	}
+	if err != nil {
+		HandleError(c, http.StatusBadGateway, err)
+		return
+	}
+
+	// Lax, since the identity provider redirects back with a top-level GET
+	c.SetCookie(ssoStateCookie, state, int(service.SSOLoginTimeout.Seconds()), ssoStateCookiePath, "",
+		protocol.CookieSameSiteLaxMode, h.service.SecureCallback(), true)
+	c.Redirect(http.StatusFound, []byte(authURL))
+}
+
+// Callback completes the login started by Login in the same browser and
+// redirects to the frontend with the access and refresh tokens, or with an
+// error.
+func (h *SSOHandler) Callback(ctx context.Context, c *app.RequestContext) {
+	state := c.Query("state")
+	browserState := c.Cookie(ssoStateCookie)
+	h.clearStateCookie(c)
+
+	fragment := url.Values{}
+	if idpErr := c.Query("error"); idpErr != "" {
+		fragment.Set("error", idpErr+": "+c.Query("error_description"))
+	} else if len(browserState) == 0 || subtle.ConstantTimeCompare(browserState, []byte(state)) != 1 {
+		recordLogin(c, h.audit, "", nil, errSSOStateMismatch)
+		fragment.Set("error", errSSOStateMismatch.Error())
+	} else if resp, err := h.service.Complete(ctx, state, c.Query("code")); err != nil {
+		recordLogin(c, h.audit, "", nil, err)
+		fragment.Set("error", err.Error())
+	} else {
+		recordLogin(c, h.audit, resp.User.Username, resp.User, nil)
+		fragment.Set("token", resp.Token)
+		fragment.Set("refresh_token", resp.RefreshToken)
+		fragment.Set("expires_in", strconv.FormatInt(resp.ExpiresIn, 10))
+	}
+
+	c.Redirect(http.StatusFound, []byte(h.redirectURL+"#"+fragment.Encode()))
+}
+
+func (h *SSOHandler) clearStateCookie(c *app.RequestContext) {
+	cookie := protocol.AcquireCookie()
+	defer protocol.ReleaseCookie(cookie)
+	cookie.SetKey(ssoStateCookie)
+	cookie.SetPath(ssoStateCookiePath)
+	cookie.SetExpire(protocol.CookieExpireDelete)
+	cookie.SetSecure(h.service.SecureCallback())
+	cookie.SetHTTPOnly(true)
+	c.Response.Header.SetCookie(cookie)
+}
@@ -2,8 +2,11 @@ package handler
 
 import (
 	"context"
+	"gogo-scheduler/internal/model"
 	"gogo-scheduler/internal/service"
+	"mime"
 	"net/http"
+	"path"
 	"strconv"
 
 	"github.com/cloudwego/hertz/pkg/app"
@@ -12,10 +15,11 @@ import (
 
 type TaskHandler struct {
 	service *service.ScriptService
+	audit   *service.AuditService
 }
 
-func NewTaskHandler(service *service.ScriptService) *TaskHandler {
-	return &TaskHandler{service: service}
+func NewTaskHandler(service *service.ScriptService, audit *service.AuditService) *TaskHandler {
+	return &TaskHandler{service: service, audit: audit}
 }
 
 func (h *TaskHandler) ListTasks(ctx context.Context, c *app.RequestContext) {
@@ -26,7 +30,13 @@ func (h *TaskHandler) ListTasks(ctx context.Context, c *app.RequestContext) {
 		}
 	}
 
-	tasks, err := h.service.ListTasks(scriptID)
+	scope, ok := requestScope(c)
+	if !ok {
+		return
+	}
+
+	page, pageSize := parsePage(c)
+	tasks, err := h.service.ListTasks(ctx, scope, scriptID, page, pageSize)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -42,9 +52,14 @@ func (h *TaskHandler) GetTask(ctx context.Context, c *app.RequestContext) {
 		return
 	}
 
-	task, err := h.service.GetTask(id)
+	scope, ok := requestScope(c)
+	if !ok {
+		return
+	}
+
+	task, err := h.service.GetTask(ctx, scope, id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		HandleScriptError(c, err)
 		return
 	}
 
@@ -58,18 +73,72 @@ func (h *TaskHandler) RerunTask(ctx context.Context, c *app.RequestContext) {
 		return
 	}
 
-	output, err := h.service.RerunTask(id)
+	scope, ok := requestScope(c)
+	if !ok {
+		return
+	}
+
+	task, err := h.service.RerunTask(ctx, scope, id)
+	var output int64
+	if task != nil {
+		output = task.ID
+	}
+	recordAudit(c, h.audit, model.AuditTaskRerun, "task", id, nil, gin.H{"task_id": output}, err)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		HandleScriptError(c, err)
 		return
 	}
 
+	message := "Task rerun started"
+	if task.Status == "awaiting_approval" {
+		message = "Task rerun awaiting approval"
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Task rerun started",
+		"message": message,
 		"task_id": output,
 	})
 }
 
+func (h *TaskHandler) ApproveTask(ctx context.Context, c *app.RequestContext) {
+	h.decide(ctx, c, model.AuditTaskApprove, h.service.ApproveTask)
+}
+
+func (h *TaskHandler) RejectTask(ctx context.Context, c *app.RequestContext) {
+	h.decide(ctx, c, model.AuditTaskReject, h.service.RejectTask)
+}
+
+// decide approves or rejects a task awaiting approval. The body may hold a
+// comment.
+func (h *TaskHandler) decide(ctx context.Context, c *app.RequestContext, action string, decide func(context.Context, model.ProjectScope, int64, string) (*model.Task, error)) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req model.ApprovalRequest
+	if len(c.Request.Body()) > 0 {
+		if err := c.BindJSON(&req); err != nil {
+			HandleError(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	scope, ok := requestScope(c)
+	if !ok {
+		return
+	}
+
+	task, err := decide(ctx, scope, id, req.Comment)
+	after := gin.H{"comment": req.Comment}
+	recordAudit(c, h.audit, action, "task", id, nil, after, err)
+	if err != nil {
+		HandleScriptError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
+
 func (h *TaskHandler) DeleteTask(ctx context.Context, c *app.RequestContext) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
@@ -77,9 +146,15 @@ func (h *TaskHandler) DeleteTask(ctx context.Context, c *app.RequestContext) {
 		return
 	}
 
-	err = h.service.DeleteTask(id)
+	scope, ok := requestScope(c)
+	if !ok {
+		return
+	}
+
+	err = h.service.DeleteTask(ctx, scope, id)
+	recordAudit(c, h.audit, model.AuditTaskDelete, "task", id, nil, nil, err)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		HandleScriptError(c, err)
 		return
 	}
 
@@ -87,3 +162,100 @@ func (h *TaskHandler) DeleteTask(ctx context.Context, c *app.RequestContext) {
 		"message": "Task deleted successfully",
 	})
 }
+
+// GetTaskLogs returns a task log as plain text. The stream query parameter
+// selects stdout, stderr or the timestamped combined log (the default). The
+// optional offset and limit query parameters select a byte range; a negative
+// offset counts from the end. The full size of the stream is returned in the
+// X-Log-Size header.
+func (h *TaskHandler) GetTaskLogs(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	stream := c.DefaultQuery("stream", service.StreamCombined)
+	if !service.IsLogStream(stream) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid stream"})
+		return
+	}
+
+	var offset, limit int64
+	if v := c.Query("offset"); v != "" {
+		if offset, err = strconv.ParseInt(v, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+			return
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		if limit, err = strconv.ParseInt(v, 10, 64); err != nil || limit < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+	}
+
+	scope, ok := requestScope(c)
+	if !ok {
+		return
+	}
+
+	data, total, err := h.service.GetTaskLog(ctx, scope, id, stream, offset, limit)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("X-Log-Size", strconv.FormatInt(total, 10))
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", data)
+}
+
+func (h *TaskHandler) ListArtifacts(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	scope, ok := requestScope(c)
+	if !ok {
+		return
+	}
+
+	artifacts, err := h.service.ListArtifacts(ctx, scope, id)
+	if err != nil {
+		HandleScriptError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, artifacts)
+}
+
+func (h *TaskHandler) DownloadArtifact(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	artifactID, err := strconv.ParseInt(c.Param("artifact_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid artifact id"})
+		return
+	}
+
+	scope, ok := requestScope(c)
+	if !ok {
+		return
+	}
+
+	artifact, body, err := h.service.OpenArtifact(ctx, scope, id, artifactID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "artifact not found"})
+		return
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(artifact.Name)}))
+	c.SetContentType("application/octet-stream")
+	// the body stream is closed by hertz once the response is written
+	c.SetBodyStream(body, int(artifact.Size))
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"gogo-scheduler/internal/tracing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingMiddleware traces every request in a server span, continuing the
+// caller's trace if the request has a traceparent header. Handlers get the
+// span in their context.
+func TracingMiddleware() app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		c = tracing.Extract(c, headerCarrier{&ctx.Request.Header})
+
+		method := string(ctx.Method())
+		route := ctx.FullPath()
+		name := method
+		if route != "" {
+			name += " " + route
+		}
+		c, span := tracing.Start(c, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", string(ctx.Request.URI().Path())),
+				attribute.String("client.address", ctx.ClientIP()),
+			),
+		)
+		defer span.End()
+
+		ctx.Next(c)
+
+		status := ctx.Response.StatusCode()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
+// headerCarrier reads and writes trace context in Hertz request headers.
+type headerCarrier struct {
+	header *protocol.RequestHeader
+}
+
+func (h headerCarrier) Get(key string) string {
+	return string(h.header.Peek(key))
+}
+
+func (h headerCarrier) Set(key, value string) {
+	h.header.Set(key, value)
+}
+
+func (h headerCarrier) Keys() []string {
+	var keys []string
+	h.header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
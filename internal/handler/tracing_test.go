@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"gogo-scheduler/internal/tracing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracingMiddleware(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.Install(exporter)
+	defer provider.Shutdown(context.Background())
+
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.Use(TracingMiddleware())
+	var handlerSpan trace.SpanContext
+	engine.GET("/api/tasks/:id", func(ctx context.Context, c *app.RequestContext) {
+		handlerSpan = trace.SpanContextFromContext(ctx)
+		c.String(http.StatusOK, "ok")
+	})
+	engine.GET("/api/fail", func(ctx context.Context, c *app.RequestContext) {
+		c.String(http.StatusInternalServerError, "failed")
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	ut.PerformRequest(engine, http.MethodGet, "/api/tasks/7", nil,
+		ut.Header{Key: "traceparent", Value: "00-" + traceID + "-00f067aa0ba902b7-01"})
+	ut.PerformRequest(engine, http.MethodGet, "/api/fail", nil)
+	if err := provider.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(spans))
+	}
+	get, fail := spans[0], spans[1]
+	if get.Name != "GET /api/tasks/:id" || get.SpanKind != trace.SpanKindServer {
+		t.Errorf("span %q of kind %v, want a server span named after the route", get.Name, get.SpanKind)
+	}
+	if get.SpanContext.TraceID().String() != traceID || get.Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("span does not continue the caller's trace: %v, parent %v", get.SpanContext.TraceID(), get.Parent.SpanID())
+	}
+	if handlerSpan.SpanID() != get.SpanContext.SpanID() {
+		t.Error("the handler context does not carry the request span")
+	}
+	attrs := attribute.NewSet(get.Attributes...)
+	if status, _ := attrs.Value("http.response.status_code"); status.AsInt64() != http.StatusOK {
+		t.Errorf("http.response.status_code = %v", status.Emit())
+	}
+	if path, _ := attrs.Value("url.path"); path.AsString() != "/api/tasks/7" {
+		t.Errorf("url.path = %q", path.AsString())
+	}
+
+	if fail.Status.Code != codes.Error {
+		t.Errorf("status of a 500 response = %+v, want an error", fail.Status)
+	}
+	if fail.Parent.IsValid() {
+		t.Error("a request without traceparent has a parent span")
+	}
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/service"
+	"net/http"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// TwoFactorHandler lets users manage TOTP two-factor authentication and
+// administrators require it. Like API tokens, it can only be managed from a
+// login session.
+type TwoFactorHandler struct {
+	authService *service.AuthService
+	audit       *service.AuditService
+}
+
+func NewTwoFactorHandler(authService *service.AuthService, audit *service.AuditService) *TwoFactorHandler {
+	return &TwoFactorHandler{authService: authService, audit: audit}
+}
+
+// Verify completes a login that returned a two-factor challenge.
+func (h *TwoFactorHandler) Verify(ctx context.Context, c *app.RequestContext) {
+	var req model.TwoFactorVerifyRequest
+	if err := c.BindJSON(&req); err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	resp, err := h.authService.CompleteTwoFactor(req.Challenge, req.Code, c.ClientIP())
+	if err != nil {
+		recordLogin(c, h.audit, "", nil, err)
+		loginError(c, err)
+		return
+	}
+	recordLogin(c, h.audit, resp.User.Username, resp.User, nil)
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *TwoFactorHandler) Enroll(ctx context.Context, c *app.RequestContext) {
+	user, ok := h.sessionUser(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.authService.EnrollTOTP(user)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *TwoFactorHandler) Activate(ctx context.Context, c *app.RequestContext) {
+	user, req, ok := h.codeRequest(c)
+	if !ok {
+		return
+	}
+
+	codes, err := h.authService.ActivateTOTP(user, req.Code)
+	recordAudit(c, h.audit, model.AuditTwoFactorEnable, "user", user.ID, nil, nil, err)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (h *TwoFactorHandler) Disable(ctx context.Context, c *app.RequestContext) {
+	user, req, ok := h.codeRequest(c)
+	if !ok {
+		return
+	}
+
+	err := h.authService.DisableTOTP(user, req.Code)
+	recordAudit(c, h.audit, model.AuditTwoFactorDisable, "user", user.ID, nil, nil, err)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(ctx context.Context, c *app.RequestContext) {
+	user, req, ok := h.codeRequest(c)
+	if !ok {
+		return
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(user, req.Code)
+	recordAudit(c, h.audit, model.AuditRecoveryCodesRegenerate, "user", user.ID, nil, nil, err)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (h *TwoFactorHandler) GetSettings(ctx context.Context, c *app.RequestContext) {
+	settings, err := h.authService.SecuritySettings()
+	if err != nil {
+		HandleError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+func (h *TwoFactorHandler) UpdateSettings(ctx context.Context, c *app.RequestContext) {
+	var req model.SecuritySettingsRequest
+	if err := c.BindJSON(&req); err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	before, _ := h.authService.SecuritySettings()
+	settings, err := h.authService.UpdateSecuritySettings(req)
+	recordAudit(c, h.audit, model.AuditSettingsUpdate, "settings", "security", before, settings, err)
+	if err != nil {
+		HandleError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+func (h *TwoFactorHandler) codeRequest(c *app.RequestContext) (*model.User, model.TwoFactorCodeRequest, bool) {
+	var req model.TwoFactorCodeRequest
+	user, ok := h.sessionUser(c)
+	if !ok {
+		return nil, req, false
+	}
+	if err := c.BindJSON(&req); err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return nil, req, false
+	}
+	return user, req, true
+}
+
+func (h *TwoFactorHandler) sessionUser(c *app.RequestContext) (*model.User, bool) {
+	if _, ok := currentAPIToken(c); ok {
+		HandleError(c, http.StatusForbidden, errors.New("API tokens cannot manage two-factor authentication"))
+		return nil, false
+	}
+	user, ok := currentUser(c)
+	if !ok {
+		HandleError(c, http.StatusUnauthorized, errors.New("user not found"))
+		return nil, false
+	}
+	return user, true
+}
@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+type UserHandler struct {
+	service *service.UserService
+	audit   *service.AuditService
+}
+
+func NewUserHandler(service *service.UserService, audit *service.AuditService) *UserHandler {
+	return &UserHandler{service: service, audit: audit}
+}
+
+func (h *UserHandler) ListUsers(ctx context.Context, c *app.RequestContext) {
+	users, err := h.service.ListUsers()
+	if err != nil {
+		HandleError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+func (h *UserHandler) UpdateRole(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	var req struct {
+		Role model.Role `json:"role" binding:"required"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	before, _ := h.service.GetUser(id)
+	user, err := h.service.SetRole(id, req.Role)
+	recordAudit(c, h.audit, model.AuditUserRoleChange, "user", id, before, user, err)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *UserHandler) CreateUser(ctx context.Context, c *app.RequestContext) {
+	var req model.CreateUserRequest
+	if err := c.BindJSON(&req); err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := h.service.CreateUser(req)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+	recordAudit(c, h.audit, model.AuditUserCreate, "user", user.ID, nil, user, nil)
+
+	c.JSON(http.StatusCreated, user)
+}
+
+func (h *UserHandler) DisableUser(ctx context.Context, c *app.RequestContext) {
+	h.setDisabled(c, true)
+}
+
+func (h *UserHandler) EnableUser(ctx context.Context, c *app.RequestContext) {
+	h.setDisabled(c, false)
+}
+
+func (h *UserHandler) ResetPassword(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	var req struct {
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	err = h.service.ResetPassword(id, req.Password)
+	recordAudit(c, h.audit, model.AuditUserPasswordReset, "user", id, nil, nil, err)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]string{"message": "password reset successfully"})
+}
+
+// ResetTwoFactor turns off two-factor authentication for another user.
+func (h *UserHandler) ResetTwoFactor(ctx context.Context, c *app.RequestContext) {
+	id, ok := h.otherUserID(c)
+	if !ok {
+		return
+	}
+
+	err := h.service.ResetTwoFactor(id)
+	recordAudit(c, h.audit, model.AuditUserTwoFactorReset, "user", id, nil, nil, err)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *UserHandler) DeleteUser(ctx context.Context, c *app.RequestContext) {
+	id, ok := h.otherUserID(c)
+	if !ok {
+		return
+	}
+	admin, ok := requestUser(c)
+	if !ok {
+		return
+	}
+
+	before, _ := h.service.GetUser(id)
+	err := h.service.DeleteUser(id, admin.ID)
+	recordAudit(c, h.audit, model.AuditUserDelete, "user", id, before, nil, err)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *UserHandler) setDisabled(c *app.RequestContext, disabled bool) {
+	id, ok := h.otherUserID(c)
+	if !ok {
+		return
+	}
+
+	action := model.AuditUserEnable
+	if disabled {
+		action = model.AuditUserDisable
+	}
+	before, _ := h.service.GetUser(id)
+	user, err := h.service.SetDisabled(id, disabled)
+	recordAudit(c, h.audit, action, "user", id, before, user, err)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// otherUserID parses the user ID from the path and rejects requests where
+// administrators target their own account.
+func (h *UserHandler) otherUserID(c *app.RequestContext) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return 0, false
+	}
+	if user, ok := currentUser(c); ok && user.ID == id {
+		HandleError(c, http.StatusBadRequest, errors.New("cannot perform this action on your own account"))
+		return 0, false
+	}
+	return id, true
+}
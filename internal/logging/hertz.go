@@ -0,0 +1,79 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+// hertzLogger passes the logs of Hertz on to slog. Trace and notice logs
+// become debug and info records; the level and output are those of slog.
+type hertzLogger struct{}
+
+func (hertzLogger) log(ctx context.Context, level slog.Level, msg string) {
+	slog.Log(ctx, level, msg)
+}
+
+func (l hertzLogger) fatal(ctx context.Context, msg string) {
+	l.log(ctx, slog.LevelError, msg)
+	os.Exit(1)
+}
+
+func (l hertzLogger) Trace(v ...any)  { l.log(context.Background(), slog.LevelDebug, fmt.Sprint(v...)) }
+func (l hertzLogger) Debug(v ...any)  { l.log(context.Background(), slog.LevelDebug, fmt.Sprint(v...)) }
+func (l hertzLogger) Info(v ...any)   { l.log(context.Background(), slog.LevelInfo, fmt.Sprint(v...)) }
+func (l hertzLogger) Notice(v ...any) { l.log(context.Background(), slog.LevelInfo, fmt.Sprint(v...)) }
+func (l hertzLogger) Warn(v ...any)   { l.log(context.Background(), slog.LevelWarn, fmt.Sprint(v...)) }
+func (l hertzLogger) Error(v ...any)  { l.log(context.Background(), slog.LevelError, fmt.Sprint(v...)) }
+func (l hertzLogger) Fatal(v ...any)  { l.fatal(context.Background(), fmt.Sprint(v...)) }
+
+func (l hertzLogger) Tracef(format string, v ...any) {
+	l.log(context.Background(), slog.LevelDebug, fmt.Sprintf(format, v...))
+}
+func (l hertzLogger) Debugf(format string, v ...any) {
+	l.log(context.Background(), slog.LevelDebug, fmt.Sprintf(format, v...))
+}
+func (l hertzLogger) Infof(format string, v ...any) {
+	l.log(context.Background(), slog.LevelInfo, fmt.Sprintf(format, v...))
+}
+func (l hertzLogger) Noticef(format string, v ...any) {
+	l.log(context.Background(), slog.LevelInfo, fmt.Sprintf(format, v...))
+}
+func (l hertzLogger) Warnf(format string, v ...any) {
+	l.log(context.Background(), slog.LevelWarn, fmt.Sprintf(format, v...))
+}
+func (l hertzLogger) Errorf(format string, v ...any) {
+	l.log(context.Background(), slog.LevelError, fmt.Sprintf(format, v...))
+}
+func (l hertzLogger) Fatalf(format string, v ...any) {
+	l.fatal(context.Background(), fmt.Sprintf(format, v...))
+}
+
+func (l hertzLogger) CtxTracef(ctx context.Context, format string, v ...any) {
+	l.log(ctx, slog.LevelDebug, fmt.Sprintf(format, v...))
+}
+func (l hertzLogger) CtxDebugf(ctx context.Context, format string, v ...any) {
+	l.log(ctx, slog.LevelDebug, fmt.Sprintf(format, v...))
+}
+func (l hertzLogger) CtxInfof(ctx context.Context, format string, v ...any) {
+	l.log(ctx, slog.LevelInfo, fmt.Sprintf(format, v...))
+}
+func (l hertzLogger) CtxNoticef(ctx context.Context, format string, v ...any) {
+	l.log(ctx, slog.LevelInfo, fmt.Sprintf(format, v...))
+}
+func (l hertzLogger) CtxWarnf(ctx context.Context, format string, v ...any) {
+	l.log(ctx, slog.LevelWarn, fmt.Sprintf(format, v...))
+}
+func (l hertzLogger) CtxErrorf(ctx context.Context, format string, v ...any) {
+	l.log(ctx, slog.LevelError, fmt.Sprintf(format, v...))
+}
+func (l hertzLogger) CtxFatalf(ctx context.Context, format string, v ...any) {
+	l.fatal(ctx, fmt.Sprintf(format, v...))
+}
+
+func (hertzLogger) SetLevel(hlog.Level) {}
+func (hertzLogger) SetOutput(io.Writer) {}
@@ -0,0 +1,80 @@
+// Package metrics defines the Prometheus metrics of the scheduler. They are
+// registered with the default registry and served on /metrics.
+package metrics
+
+import (
+	"github.com/panjf2000/ants/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "gogo"
+
+var (
+	// TaskRuns counts finished runs by script name and final status.
+	TaskRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "task_runs_total",
+		Help:      "Finished task runs by script and status.",
+	}, []string{"script", "status"})
+
+	TaskDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "task_duration_seconds",
+		Help:      "Duration of task runs by script and status.",
+		Buckets:   []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 600, 1800, 3600},
+	}, []string{"script", "status"})
+
+	// TaskQueueWait is the time between a run being queued, or approved,
+	// and a worker picking it up.
+	TaskQueueWait = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "task_queue_wait_seconds",
+		Help:      "Time task runs wait for a worker.",
+		Buckets:   []float64{0.001, 0.01, 0.1, 0.5, 1, 5, 10, 30, 60, 300},
+	})
+
+	TasksRunning = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "tasks_running",
+		Help:      "Task runs being executed.",
+	})
+
+	TasksPending = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "tasks_pending",
+		Help:      "Task runs waiting for a worker.",
+	})
+
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "code"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+)
+
+// RegisterWorkerPool exposes the size and usage of the pool running tasks.
+func RegisterWorkerPool(pool *ants.Pool) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "worker_pool_capacity",
+		Help:      "Capacity of the task worker pool.",
+	}, func() float64 { return float64(pool.Cap()) })
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "worker_pool_running",
+		Help:      "Workers of the task worker pool, including idle workers not yet purged.",
+	}, func() float64 { return float64(pool.Running()) })
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "worker_pool_free",
+		Help:      "Free workers of the task worker pool.",
+	}, func() float64 { return float64(pool.Free()) })
+}
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// APITokenPrefix marks bearer tokens that are personal API tokens rather
+// than JWTs.
+const APITokenPrefix = "ggs_"
+
+// APIToken is a long-lived personal token for automation. Only a hash of the
+// token is stored; the token itself is shown once when it is created.
+type APIToken struct {
+	ID         int64        `json:"id" gorm:"primaryKey"`
+	UserID     int64        `json:"user_id" gorm:"not null;index"`
+	Name       string       `json:"name" gorm:"not null"`
+	Prefix     string       `json:"prefix"` // first characters of the token, to tell tokens apart
+	TokenHash  string       `json:"-" gorm:"not null;uniqueIndex"`
+	Scopes     []Permission `json:"scopes" gorm:"serializer:json"`
+	ExpiresAt  *time.Time   `json:"expires_at"`
+	LastUsedAt *time.Time   `json:"last_used_at"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// HasScope reports whether the token was granted the permission. A token
+// never grants more than its owner's role allows.
+func (t *APIToken) HasScope(p Permission) bool {
+	for _, scope := range t.Scopes {
+		if scope == p {
+			return true
+		}
+	}
+	return false
+}
+
+type CreateAPITokenRequest struct {
+	Name      string       `json:"name" binding:"required"`
+	Scopes    []Permission `json:"scopes"`
+	ExpiresAt *time.Time   `json:"expires_at"`
+}
+
+type CreateAPITokenResponse struct {
+	Token    string   `json:"token"`
+	APIToken APIToken `json:"api_token"`
+}
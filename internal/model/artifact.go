@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// Artifact is a file collected from a task's workspace after it ran.
+type Artifact struct {
+	ID         int64     `json:"id" gorm:"primaryKey"`
+	TaskID     int64     `json:"task_id" gorm:"not null;index"`
+	Name       string    `json:"name" gorm:"not null"` // path relative to the workspace
+	Size       int64     `json:"size"`
+	StorageKey string    `json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+}
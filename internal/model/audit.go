@@ -0,0 +1,87 @@
+package model
+
+import "time"
+
+// Audit actions.
+const (
+	AuditLogin                   = "auth.login"
+	AuditLogout                  = "auth.logout"
+	AuditPasswordChange          = "auth.change_password"
+	AuditTwoFactorEnable         = "auth.2fa_enable"
+	AuditTwoFactorDisable        = "auth.2fa_disable"
+	AuditRecoveryCodesRegenerate = "auth.2fa_recovery_codes"
+	AuditScriptCreate            = "script.create"
+	AuditScriptUpdate            = "script.update"
+	AuditScriptDelete            = "script.delete"
+	AuditScriptRun               = "script.run"
+	AuditScriptShare             = "script.share"
+	AuditScriptUnshare           = "script.unshare"
+	AuditScriptTransfer          = "script.transfer"
+	AuditScriptNotifications     = "script.notifications"
+	AuditTaskApprove             = "task.approve"
+	AuditTaskReject              = "task.reject"
+	AuditTaskRerun               = "task.rerun"
+	AuditTaskDelete              = "task.delete"
+	AuditUserCreate              = "user.create"
+	AuditUserRoleChange          = "user.role_change"
+	AuditUserDisable             = "user.disable"
+	AuditUserEnable              = "user.enable"
+	AuditUserPasswordReset       = "user.password_reset"
+	AuditUserTwoFactorReset      = "user.2fa_reset"
+	AuditUserDelete              = "user.delete"
+	AuditGroupCreate             = "group.create"
+	AuditGroupDelete             = "group.delete"
+	AuditGroupMemberAdd          = "group.member_add"
+	AuditGroupMemberRemove       = "group.member_remove"
+	AuditProjectCreate           = "project.create"
+	AuditProjectUpdate           = "project.update"
+	AuditProjectDelete           = "project.delete"
+	AuditProjectMemberSet        = "project.member_set"
+	AuditProjectMemberRemove     = "project.member_remove"
+	AuditChannelCreate           = "channel.create"
+	AuditChannelUpdate           = "channel.update"
+	AuditChannelDelete           = "channel.delete"
+	AuditTokenCreate             = "token.create"
+	AuditTokenRevoke             = "token.revoke"
+	AuditRetentionUpdate         = "retention.update"
+	AuditRetentionDelete         = "retention.delete"
+	AuditRetentionRun            = "retention.run"
+	AuditSettingsUpdate          = "settings.update"
+)
+
+// AuditEvent records who did what to which object. Events are only ever
+// appended.
+type AuditEvent struct {
+	ID         int64         `json:"id" gorm:"primaryKey"`
+	CreatedAt  time.Time     `json:"created_at" gorm:"index"`
+	UserID     *int64        `json:"user_id" gorm:"index"` // nil for failed logins
+	Username   string        `json:"username"`             // kept when the user is deleted
+	APITokenID *int64        `json:"api_token_id,omitempty"`
+	IP         string        `json:"ip"`
+	Action     string        `json:"action" gorm:"not null;index"`
+	TargetType string        `json:"target_type" gorm:"index:idx_audit_target"`
+	TargetID   string        `json:"target_id" gorm:"index:idx_audit_target"`
+	Success    bool          `json:"success"`
+	Error      string        `json:"error,omitempty"`
+	Changes    []AuditChange `json:"changes,omitempty" gorm:"serializer:json"`
+}
+
+// AuditChange is a field that differs between the object before and after
+// the action.
+type AuditChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+type AuditQuery struct {
+	Page       int
+	PageSize   int
+	UserID     *int64
+	Username   string
+	Action     string // exact action, or a prefix ending in "." such as "script."
+	TargetType string
+	TargetID   string
+	Since      *time.Time
+	Until      *time.Time
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// Group is a named set of users that scripts can be shared with.
+type Group struct {
+	ID          int64     `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"unique;not null"`
+	Description string    `json:"description"`
+	MemberIDs   []int64   `json:"member_ids" gorm:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type GroupMember struct {
+	GroupID int64 `gorm:"primaryKey"`
+	UserID  int64 `gorm:"primaryKey;index"`
+}
+
+type GroupRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+type GroupMemberRequest struct {
+	UserID int64 `json:"user_id" binding:"required"`
+}
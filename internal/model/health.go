@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+const (
+	HealthOK      = "ok"
+	HealthFailing = "failing"
+)
+
+// HealthReport is the readiness of the scheduler and its components.
+type HealthReport struct {
+	Status     string                     `json:"status"`
+	CheckedAt  time.Time                  `json:"checked_at"`
+	Components map[string]ComponentHealth `json:"components"`
+}
+
+type ComponentHealth struct {
+	Status  string         `json:"status"`
+	Error   string         `json:"error,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+}
@@ -0,0 +1,213 @@
+package model
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Task events that scripts can send notifications for.
+const (
+	EventTaskStarted   = "task.started"
+	EventTaskSucceeded = "task.succeeded"
+	EventTaskFailed    = "task.failed"
+	EventTaskTimedOut  = "task.timed_out"
+)
+
+// TaskEvents lists all task events.
+var TaskEvents = []string{EventTaskStarted, EventTaskSucceeded, EventTaskFailed, EventTaskTimedOut}
+
+// Notification channel types.
+const (
+	ChannelWebhook = "webhook"
+	ChannelEmail   = "email"
+)
+
+// NotificationChannel is a destination for the task notifications of a
+// project's scripts.
+type NotificationChannel struct {
+	ID        int64          `json:"id" gorm:"primaryKey"`
+	ProjectID int64          `json:"project_id" gorm:"not null;index"`
+	Name      string         `json:"name" gorm:"not null"`
+	Type      string         `json:"type" gorm:"not null"` // webhook or email
+	Enabled   bool           `json:"enabled"`
+	Webhook   *WebhookConfig `json:"webhook,omitempty" gorm:"serializer:json"`
+	Email     *EmailConfig   `json:"email,omitempty" gorm:"serializer:json"`
+	Secret    string         `json:"-"`                   // signs webhook payloads; the SMTP password of email channels
+	HasSecret bool           `json:"has_secret" gorm:"-"` // set when returned by the API
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// Webhook formats for chat incoming webhooks. Webhooks without a format
+// receive the Notification as JSON.
+const (
+	WebhookFormatSlack    = "slack" // also Mattermost and Rocket.Chat
+	WebhookFormatTeams    = "teams"
+	WebhookFormatDingTalk = "dingtalk"
+	WebhookFormatFeishu   = "feishu"
+)
+
+// WebhookConfig holds the destination of a webhook channel. The URL and
+// header values can hold credentials, so the API only returns them
+// redacted.
+type WebhookConfig struct {
+	URL     string            `json:"url"`
+	Format  string            `json:"format,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// RedactedValue replaces header values, and the path and query of URLs, in
+// API responses.
+const RedactedValue = "[redacted]"
+
+// Redacted returns a copy of the channel without its secrets: the secret
+// itself, the path, query and user info of webhook URLs, which often hold
+// tokens, and the values of webhook headers.
+func (c *NotificationChannel) Redacted() *NotificationChannel {
+	redacted := *c
+	redacted.HasSecret = c.Secret != ""
+	redacted.Secret = ""
+	if c.Webhook != nil {
+		webhook := *c.Webhook
+		webhook.URL = redactURL(c.Webhook.URL)
+		if c.Webhook.Headers != nil {
+			webhook.Headers = make(map[string]string, len(c.Webhook.Headers))
+			for name := range c.Webhook.Headers {
+				webhook.Headers[name] = RedactedValue
+			}
+		}
+		redacted.Webhook = &webhook
+	}
+	return &redacted
+}
+
+// Restore puts back the URL and header values of current that were sent
+// back redacted, so that a channel can be updated with what the API
+// returned for it. Header values are only restored while the URL keeps
+// its scheme and host, so that they are never sent to a new destination;
+// otherwise they must be sent again.
+func (c *WebhookConfig) Restore(current *WebhookConfig) error {
+	if c.URL == redactURL(current.URL) {
+		c.URL = current.URL
+	}
+	sameOrigin := origin(c.URL) != "" && origin(c.URL) == origin(current.URL)
+	for name, value := range c.Headers {
+		stored, ok := current.Headers[name]
+		if !ok || value != RedactedValue {
+			continue
+		}
+		if !sameOrigin {
+			return fmt.Errorf("the value of header %s must be sent again when the webhook host changes", name)
+		}
+		c.Headers[name] = stored
+	}
+	return nil
+}
+
+// origin returns the scheme and host of a URL, or "" if it has none.
+func origin(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+// redactURL keeps the scheme and host of a URL.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return RedactedValue
+	}
+	return u.Scheme + "://" + u.Host + "/" + RedactedValue
+}
+
+// EmailConfig holds the SMTP server and the messages of an email channel.
+// Subject and Body are text/template templates executed with the
+// Notification; empty templates use the defaults.
+type EmailConfig struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port"`
+	StartTLS bool     `json:"starttls"`
+	Username string   `json:"username,omitempty"` // authenticates with the channel secret as password
+	From     string   `json:"from"`
+	To       []string `json:"to,omitempty"` // used when a script sets no recipients
+	Subject  string   `json:"subject,omitempty"`
+	Body     string   `json:"body,omitempty"`
+}
+
+type NotificationChannelRequest struct {
+	Name    string         `json:"name" binding:"required"`
+	Type    string         `json:"type" binding:"required"`
+	Enabled *bool          `json:"enabled"` // defaults to true
+	Webhook *WebhookConfig `json:"webhook"`
+	Email   *EmailConfig   `json:"email"`
+	// Secret replaces the signing secret or SMTP password if set; ""
+	// removes it.
+	Secret *string `json:"secret"`
+}
+
+// ScriptNotification sends a script's task events to a channel. A script
+// can subscribe to a channel more than once to send different events to
+// different recipients.
+type ScriptNotification struct {
+	ID         int64     `json:"id" gorm:"primaryKey"`
+	ScriptID   int64     `json:"script_id" gorm:"not null;index"`
+	ChannelID  int64     `json:"channel_id" gorm:"not null;index"`
+	Events     []string  `json:"events" gorm:"serializer:json"`
+	Recipients []string  `json:"recipients,omitempty" gorm:"serializer:json"` // email channels only
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type ScriptNotificationRequest struct {
+	ChannelID  int64    `json:"channel_id" binding:"required"`
+	Events     []string `json:"events" binding:"required"`
+	Recipients []string `json:"recipients"` // defaults to the channel's recipients
+}
+
+// NotificationDelivery records the delivery of one notification to a
+// channel, including its retries.
+type NotificationDelivery struct {
+	ID             int64     `json:"id" gorm:"primaryKey"`
+	ChannelID      int64     `json:"channel_id" gorm:"not null;index"`
+	TaskID         int64     `json:"task_id" gorm:"index"` // 0 for test notifications
+	Event          string    `json:"event"`
+	Recipients     []string  `json:"recipients,omitempty" gorm:"serializer:json"` // email channels only
+	Status         string    `json:"status"`                                      // pending, delivered or failed
+	Attempts       int       `json:"attempts"`
+	ResponseStatus int       `json:"response_status,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Notification is the payload sent to channels.
+type Notification struct {
+	Event     string             `json:"event"`
+	Timestamp time.Time          `json:"timestamp"`
+	Task      NotificationTask   `json:"task"`
+	Script    NotificationScript `json:"script"`
+}
+
+type NotificationTask struct {
+	ID          int64      `json:"id"`
+	Name        string     `json:"name"`
+	Status      string     `json:"status"`
+	StartTime   *time.Time `json:"start_time,omitempty"`
+	EndTime     *time.Time `json:"end_time,omitempty"`
+	DurationMs  int64      `json:"duration_ms,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	Output      string     `json:"output,omitempty"`       // tail of stdout
+	ErrorOutput string     `json:"error_output,omitempty"` // tail of stderr
+	RequestedBy int64      `json:"requested_by,omitempty"`
+	URL         string     `json:"url,omitempty"` // set if the public URL of the server is configured
+}
+
+type NotificationScript struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	ProjectID int64  `json:"project_id"`
+}
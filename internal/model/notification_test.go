@@ -0,0 +1,41 @@
+package model
+
+import "testing"
+
+func TestWebhookConfigRestore(t *testing.T) {
+	current := &WebhookConfig{
+		URL:     "https://hooks.example.com/services/T000/B000/secret",
+		Headers: map[string]string{"Authorization": "Bearer token"},
+	}
+	redacted := (&NotificationChannel{Webhook: current}).Redacted().Webhook
+
+	// sent back unchanged
+	update := *redacted
+	update.Headers = map[string]string{"Authorization": RedactedValue}
+	if err := update.Restore(current); err != nil {
+		t.Fatal(err)
+	}
+	if update.URL != current.URL || update.Headers["Authorization"] != "Bearer token" {
+		t.Errorf("restored %+v", update)
+	}
+
+	// a new path on the same host keeps the headers
+	update = WebhookConfig{URL: "https://HOOKS.example.com/other", Headers: map[string]string{"Authorization": RedactedValue}}
+	if err := update.Restore(current); err != nil || update.Headers["Authorization"] != "Bearer token" {
+		t.Errorf("restore on the same host = %+v, %v", update, err)
+	}
+
+	// another host must not receive the stored headers
+	for _, url := range []string{"https://attacker.example.net/collect", "http://hooks.example.com/services"} {
+		update = WebhookConfig{URL: url, Headers: map[string]string{"Authorization": RedactedValue}}
+		if err := update.Restore(current); err == nil || update.Headers["Authorization"] != RedactedValue {
+			t.Errorf("restore for %s = %+v, %v; want an error", url, update, err)
+		}
+	}
+
+	// new values are taken as they are
+	update = WebhookConfig{URL: "https://attacker.example.net/collect", Headers: map[string]string{"Authorization": "Bearer other"}}
+	if err := update.Restore(current); err != nil || update.Headers["Authorization"] != "Bearer other" {
+		t.Errorf("restore with new values = %+v, %v", update, err)
+	}
+}
@@ -0,0 +1,56 @@
+package model
+
+import "time"
+
+// DefaultProjectSlug names the project that holds scripts from before
+// projects were introduced. Every user belongs to it with their global role.
+const DefaultProjectSlug = "default"
+
+// Project is a namespace that owns scripts and their tasks. Users other
+// than administrators only see the projects they are members of.
+type Project struct {
+	ID          int64  `json:"id" gorm:"primaryKey"`
+	Slug        string `json:"slug" gorm:"unique;not null"` // used in /api/projects/:project
+	Name        string `json:"name" gorm:"not null"`
+	Description string `json:"description"`
+	// MaxConcurrentRuns limits the project's pending and running tasks;
+	// 0 means no limit.
+	MaxConcurrentRuns int       `json:"max_concurrent_runs"`
+	Role              Role      `json:"role,omitempty" gorm:"-"` // the requesting user's role in the project
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// ProjectMember gives a user a role within a project, which replaces their
+// global role on the project's routes.
+type ProjectMember struct {
+	ProjectID int64     `json:"project_id" gorm:"primaryKey"`
+	UserID    int64     `json:"user_id" gorm:"primaryKey;index"`
+	Username  string    `json:"username" gorm:"-"`
+	Role      Role      `json:"role" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateProjectRequest struct {
+	Slug              string `json:"slug" binding:"required"`
+	Name              string `json:"name" binding:"required"`
+	Description       string `json:"description"`
+	MaxConcurrentRuns int    `json:"max_concurrent_runs"`
+}
+
+type UpdateProjectRequest struct {
+	Name              string `json:"name" binding:"required"`
+	Description       string `json:"description"`
+	MaxConcurrentRuns int    `json:"max_concurrent_runs"`
+}
+
+type ProjectMemberRequest struct {
+	Role Role `json:"role" binding:"required"`
+}
+
+// ProjectScope is the user a request acts for and the project it is scoped
+// to. User.Role holds the user's role in that project.
+type ProjectScope struct {
+	ProjectID int64
+	User      *User
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// RetentionPolicy controls how long finished tasks are kept. The policy with
+// ScriptID 0 is the global default; a per-script policy overrides it.
+type RetentionPolicy struct {
+	ID             int64     `json:"id" gorm:"primaryKey"`
+	ScriptID       int64     `json:"script_id" gorm:"uniqueIndex"`
+	KeepLastN      int       `json:"keep_last_n"`      // 0 keeps any number of runs
+	KeepDays       int       `json:"keep_days"`        // 0 keeps runs regardless of age
+	KeepFailedDays int       `json:"keep_failed_days"` // failed and timed out runs are kept this long instead, outside of KeepLastN
+	PurgeDeleted   bool      `json:"purge_deleted"`    // global only: hard-delete soft-deleted rows
+	Vacuum         bool      `json:"vacuum"`           // global only: VACUUM the database after removing rows
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type RetentionPolicyRequest struct {
+	KeepLastN      int  `json:"keep_last_n"`
+	KeepDays       int  `json:"keep_days"`
+	KeepFailedDays int  `json:"keep_failed_days"`
+	PurgeDeleted   bool `json:"purge_deleted"`
+	Vacuum         bool `json:"vacuum"`
+}
+
+type RetentionReport struct {
+	DryRun        bool      `json:"dry_run"`
+	ExpiredTasks  []int64   `json:"expired_tasks"`
+	PurgedTasks   int64     `json:"purged_tasks"`
+	PurgedScripts int64     `json:"purged_scripts"`
+	Vacuumed      bool      `json:"vacuumed"`
+	StartedAt     time.Time `json:"started_at"`
+	FinishedAt    time.Time `json:"finished_at"`
+}
@@ -0,0 +1,64 @@
+package model
+
+// Role is a user's role. Each role includes the permissions of the roles
+// before it: viewer < operator < editor < admin.
+type Role string
+
+const (
+	RoleViewer   Role = "viewer"
+	RoleOperator Role = "operator"
+	RoleEditor   Role = "editor"
+	RoleAdmin    Role = "admin"
+)
+
+// Roles lists all roles from least to most privileged.
+var Roles = []Role{RoleViewer, RoleOperator, RoleEditor, RoleAdmin}
+
+type Permission string
+
+const (
+	PermissionRead       Permission = "read"       // view scripts, tasks, logs and artifacts
+	PermissionRun        Permission = "run"        // run and rerun scripts
+	PermissionEdit       Permission = "edit"       // create, update and delete scripts and tasks
+	PermissionAdminister Permission = "administer" // manage users and global settings
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleViewer:   {PermissionRead},
+	RoleOperator: {PermissionRead, PermissionRun},
+	RoleEditor:   {PermissionRead, PermissionRun, PermissionEdit},
+	RoleAdmin:    {PermissionRead, PermissionRun, PermissionEdit, PermissionAdminister},
+}
+
+func (p Permission) Valid() bool {
+	for _, granted := range rolePermissions[RoleAdmin] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Rank orders roles by privilege; higher is more privileged.
+func (r Role) Rank() int {
+	for i, role := range Roles {
+		if role == r {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r Role) Can(p Permission) bool {
+	for _, granted := range rolePermissions[r] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
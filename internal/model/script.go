@@ -7,11 +7,77 @@ import (
 )
 
 type Script struct {
-	ID        int64          `json:"id" gorm:"primaryKey"`
-	Name      string         `json:"name" gorm:"not null"`
-	Type      string         `json:"type" gorm:"not null"` // python or shell
-	Content   string         `json:"content" gorm:"not null"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	ID          int64          `json:"id" gorm:"primaryKey"`
+	Name        string         `json:"name" gorm:"not null"`
+	Type        string         `json:"type" gorm:"not null"` // python or shell
+	Content     string         `json:"content" gorm:"not null"`
+	Description string         `json:"description"`
+	Tags        []string       `json:"tags" gorm:"serializer:json"`
+	Artifacts   []string       `json:"artifacts" gorm:"serializer:json"`         // globs collected from the workspace after a run
+	OwnerID     int64          `json:"owner_id" gorm:"not null;default:0;index"` // assigned at startup for scripts from before ownership
+	ProjectID   int64          `json:"project_id" gorm:"not null;default:0;index"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+
+	// RequiresApproval holds runs until another user approves them. If
+	// Approvers is empty, anyone who can edit the script may approve.
+	RequiresApproval bool    `json:"requires_approval"`
+	Approvers        []int64 `json:"approvers" gorm:"serializer:json"` // user IDs
+
+	Timeout int `json:"timeout"` // seconds a run may take; 0 means no limit
+}
+
+type ScriptRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	Type        string   `json:"type" binding:"required"`
+	Content     string   `json:"content" binding:"required"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	Artifacts   []string `json:"artifacts"`
+
+	RequiresApproval bool    `json:"requires_approval"`
+	Approvers        []int64 `json:"approvers"`
+	Timeout          int     `json:"timeout"`
+}
+
+type ScriptQuery struct {
+	Page      int
+	PageSize  int
+	Search    string // matched against name, description and content
+	Type      string
+	Tag       string
+	Sort      string // name, updated_at or last_run_status
+	Order     string // asc or desc
+	ProjectID int64
+	// Reader limits the results to scripts the reader can read; nil means
+	// no limit.
+	Reader *ScriptReader
+}
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// NormalizePage falls back to the first page of DefaultPageSize items and
+// caps the page size at MaxPageSize.
+func NormalizePage(page, pageSize int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = DefaultPageSize
+	}
+	if pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+	return page, pageSize
+}
+
+type PageResult[T any] struct {
+	Items    []T   `json:"items"`
+	Total    int64 `json:"total"`
+	Page     int   `json:"page"`
+	PageSize int   `json:"page_size"`
 }
@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// ScriptShare grants a user or a group access to a script. Exactly one of
+// UserID and GroupID is set. Level is PermissionRead, PermissionRun or
+// PermissionEdit, each including the ones before it. A share never grants
+// more than the user's role allows.
+type ScriptShare struct {
+	ID        int64      `json:"id" gorm:"primaryKey"`
+	ScriptID  int64      `json:"script_id" gorm:"not null;index"`
+	UserID    *int64     `json:"user_id,omitempty" gorm:"index"`
+	GroupID   *int64     `json:"group_id,omitempty" gorm:"index"`
+	Level     Permission `json:"level" gorm:"not null"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// ShareLevels lists the share levels from least to most privileged.
+var ShareLevels = []Permission{PermissionRead, PermissionRun, PermissionEdit}
+
+// ShareLevelRank orders share levels; it is -1 for anything else.
+func ShareLevelRank(p Permission) int {
+	for i, level := range ShareLevels {
+		if level == p {
+			return i
+		}
+	}
+	return -1
+}
+
+type ShareRequest struct {
+	UserID  *int64     `json:"user_id"`
+	GroupID *int64     `json:"group_id"`
+	Level   Permission `json:"level" binding:"required"`
+}
+
+type TransferOwnershipRequest struct {
+	OwnerID int64 `json:"owner_id" binding:"required"`
+}
+
+// ScriptReader identifies a user and their groups when filtering scripts
+// and tasks by access.
+type ScriptReader struct {
+	UserID   int64
+	GroupIDs []int64
+}
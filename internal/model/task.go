@@ -10,8 +10,9 @@ type Task struct {
 	ID         int64          `json:"id" gorm:"primaryKey"`
 	ScriptID   int64          `json:"script_id" gorm:"not null"`
 	Script     Script         `json:"script" gorm:"foreignKey:ScriptID"`
-	Status     string         `json:"status"` // pending, running, success, failed
-	Output     string         `json:"output"`
+	ProjectID  int64          `json:"project_id" gorm:"not null;default:0;index"`
+	Status     string         `json:"status"` // awaiting_approval, pending, running, success, failed, timed_out, rejected or expired
+	Output     string         `json:"output"` // tail of stdout; full logs are in log storage
 	StartTime  *time.Time     `json:"start_time"`
 	EndTime    *time.Time     `json:"end_time"`
 	CreatedAt  time.Time      `json:"created_at"`
@@ -22,4 +23,27 @@ type Task struct {
 	LastRun    time.Time      `json:"last_run"`
 	NextRun    time.Time      `json:"next_run"`
 	Error      string         `json:"error"`
+
+	ErrorOutput   string `json:"error_output"` // tail of stderr
+	LogStored     bool   `json:"log_stored"`
+	LogSize       int64  `json:"log_size"` // size of the combined log
+	StdoutSize    int64  `json:"stdout_size"`
+	StderrSize    int64  `json:"stderr_size"`
+	LogTruncated  bool   `json:"log_truncated"`
+	LogCompressed bool   `json:"log_compressed"`
+
+	RequestedBy int64 `json:"requested_by"` // user who started the run
+	// ScriptType and ScriptContent hold the script as it was when approval
+	// was requested; an approved run executes them rather than the current
+	// script.
+	ScriptType        string     `json:"script_type,omitempty"`
+	ScriptContent     string     `json:"script_content,omitempty"`
+	ApprovalExpiresAt *time.Time `json:"approval_expires_at,omitempty"`
+	DecidedBy         *int64     `json:"decided_by,omitempty"` // user who approved or rejected the run
+	DecidedAt         *time.Time `json:"decided_at,omitempty"`
+	DecisionComment   string     `json:"decision_comment,omitempty"`
+}
+
+type ApprovalRequest struct {
+	Comment string `json:"comment"`
 }
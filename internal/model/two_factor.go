@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// RecoveryCode is a single-use code that replaces a TOTP code when the
+// authenticator device is lost. Only a hash of the code is stored.
+type RecoveryCode struct {
+	ID        int64      `json:"id" gorm:"primaryKey"`
+	UserID    int64      `json:"user_id" gorm:"not null;index"`
+	CodeHash  string     `json:"-" gorm:"not null;index"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// SecuritySettings holds instance-wide security settings. There is a
+// single row with ID 1.
+type SecuritySettings struct {
+	ID               int64     `json:"-" gorm:"primaryKey"`
+	RequireTwoFactor bool      `json:"require_two_factor" gorm:"not null;default:false"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+type SecuritySettingsRequest struct {
+	RequireTwoFactor bool `json:"require_two_factor"`
+}
+
+type TOTPEnrollResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"` // otpauth:// provisioning URI to show as a QR code
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"` // TOTP code or recovery code
+}
+
+type TwoFactorVerifyRequest struct {
+	Challenge string `json:"challenge" binding:"required"`
+	Code      string `json:"code" binding:"required"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
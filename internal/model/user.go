@@ -7,21 +7,51 @@ import (
 )
 
 type User struct {
-	ID        int64     `json:"id" gorm:"primaryKey"`
-	Username  string    `json:"username" gorm:"unique;not null"`
-	Password  string    `json:"-" gorm:"not null"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID                 int64     `json:"id" gorm:"primaryKey"`
+	Username           string    `json:"username" gorm:"unique;not null"`
+	Password           string    `json:"-" gorm:"not null"`
+	Role               Role      `json:"role" gorm:"not null;default:viewer"`
+	Disabled           bool      `json:"disabled" gorm:"not null;default:false"`
+	TokenVersion       int       `json:"-" gorm:"not null;default:0"` // bumped to invalidate all issued access tokens
+	AuthProvider       string    `json:"auth_provider" gorm:"not null;default:local"`
+	ExternalID         string    `json:"-" gorm:"index"`                                     // subject at the external identity provider
+	MustChangePassword bool      `json:"must_change_password" gorm:"not null;default:false"` // only the password can be changed until this is cleared
+	TOTPSecret         string    `json:"-"`                                                  // set on enrollment, before TOTPEnabled
+	TOTPEnabled        bool      `json:"totp_enabled" gorm:"not null;default:false"`
+	TOTPLastStep       int64     `json:"-" gorm:"not null;default:0"` // last time step used, so codes cannot be replayed
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
 }
 
+// Authentication providers a user can sign in with.
+const (
+	AuthProviderLocal = "local"
+	AuthProviderOIDC  = "oidc"
+	AuthProviderLDAP  = "ldap"
+)
+
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
 
+type CreateUserRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	Role     Role   `json:"role"`
+}
+
+// LoginResponse either carries the session tokens or, for users with
+// two-factor authentication, a challenge to complete with a code first.
 type LoginResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"` // access token lifetime in seconds
+	User         *User  `json:"user,omitempty"`
+
+	TwoFactorRequired      bool   `json:"two_factor_required,omitempty"`
+	Challenge              string `json:"challenge,omitempty"`
+	TwoFactorSetupRequired bool   `json:"two_factor_setup_required,omitempty"` // enroll before using the API
 }
 
 func (u *User) HashPassword() error {
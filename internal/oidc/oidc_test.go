@@ -0,0 +1,160 @@
+package oidc_test
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"gogo-scheduler/internal/oidc"
+	"gogo-scheduler/internal/oidc/oidctest"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const redirectURL = "http://scheduler.test/api/auth/oidc/callback"
+
+func newProvider(t *testing.T) (*oidctest.Server, *oidc.Provider) {
+	t.Helper()
+	idp := oidctest.NewServer("scheduler", "client-secret")
+	t.Cleanup(idp.Close)
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:       idp.URL,
+		ClientID:     idp.ClientID,
+		ClientSecret: idp.ClientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"openid", "profile"},
+	})
+	return idp, provider
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	idp, provider := newProvider(t)
+	idp.SetClaims(map[string]interface{}{
+		"sub":                "alice-id",
+		"preferred_username": "alice",
+		"email":              "alice@example.com",
+		"groups":             []string{"ops", "dev"},
+	})
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "nonce-1", "verifier-1")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := u.Query().Get("scope"); got != "openid profile" {
+		t.Errorf("scope = %q, want %q", got, "openid profile")
+	}
+
+	code, state, err := idp.Authorize(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != "state-1" {
+		t.Errorf("state = %q, want %q", state, "state-1")
+	}
+
+	claims, err := provider.Exchange(ctx, code, "verifier-1", "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if claims.Subject != "alice-id" || claims.PreferredUsername != "alice" || claims.Email != "alice@example.com" {
+		t.Errorf("claims = %+v", claims)
+	}
+	if groups := claims.StringList("groups"); strings.Join(groups, ",") != "ops,dev" {
+		t.Errorf("groups = %v", groups)
+	}
+
+	if _, err := provider.Exchange(ctx, code, "verifier-1", "nonce-1"); err == nil {
+		t.Error("redeeming a code twice succeeded")
+	}
+}
+
+func TestExchangeRejectsWrongVerifierAndNonce(t *testing.T) {
+	idp, provider := newProvider(t)
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, "state", "nonce", "verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _, err := idp.Authorize(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Exchange(ctx, code, "other-verifier", "nonce"); err == nil {
+		t.Error("Exchange with the wrong PKCE verifier succeeded")
+	}
+
+	code, _, err = idp.Authorize(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Exchange(ctx, code, "verifier", "other-nonce"); err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Errorf("Exchange with the wrong nonce: err = %v, want a nonce mismatch", err)
+	}
+}
+
+func TestVerifyRejectsInvalidTokens(t *testing.T) {
+	idp, provider := newProvider(t)
+	ctx := context.Background()
+	now := time.Now()
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss": idp.URL, "aud": idp.ClientID, "sub": "alice-id", "nonce": "n",
+			"iat": now.Unix(), "exp": now.Add(time.Hour).Unix(),
+		}
+	}
+
+	token, err := idp.Sign(valid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Verify(ctx, token, "n"); err != nil {
+		t.Fatalf("Verify of a valid token: %v", err)
+	}
+
+	for name, change := range map[string]func(jwt.MapClaims){
+		"wrong issuer":   func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" },
+		"wrong audience": func(c jwt.MapClaims) { c["aud"] = "other-client" },
+		"expired":        func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Hour).Unix() },
+		"no expiry":      func(c jwt.MapClaims) { delete(c, "exp") },
+		"no subject":     func(c jwt.MapClaims) { delete(c, "sub") },
+	} {
+		claims := valid()
+		change(claims)
+		token, err := idp.Sign(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := provider.Verify(ctx, token, "n"); err == nil {
+			t.Errorf("%s: Verify succeeded", name)
+		}
+	}
+
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, valid()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Verify(ctx, unsigned, "n"); err == nil {
+		t.Error("Verify of an unsigned token succeeded")
+	}
+}
+
+func TestDiscoveryRejectsIssuerMismatch(t *testing.T) {
+	idp := oidctest.NewServer("scheduler", "client-secret")
+	defer idp.Close()
+	// the same server under another name announces a different issuer
+	issuer := strings.Replace(idp.URL, "127.0.0.1", "localhost", 1)
+	provider := oidc.NewProvider(oidc.Config{Issuer: issuer, ClientID: "scheduler", RedirectURL: redirectURL})
+
+	_, err := provider.AuthCodeURL(context.Background(), "s", "n", "v")
+	if err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("AuthCodeURL: err = %v, want an issuer mismatch", err)
+	}
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"gogo-scheduler/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type APITokenRepository struct {
+	db *gorm.DB
+}
+
+func NewAPITokenRepository(db *gorm.DB) *APITokenRepository {
+	return &APITokenRepository{db: db}
+}
+
+func (r *APITokenRepository) Create(token *model.APIToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *APITokenRepository) FindByHash(hash string) (*model.APIToken, error) {
+	var token model.APIToken
+	err := r.db.Where("token_hash = ?", hash).First(&token).Error
+	return &token, err
+}
+
+func (r *APITokenRepository) ListByUser(userID int64) ([]model.APIToken, error) {
+	var tokens []model.APIToken
+	err := r.db.Where("user_id = ?", userID).Order("id").Find(&tokens).Error
+	return tokens, err
+}
+
+// Delete removes a token owned by the user and reports whether it existed.
+func (r *APITokenRepository) Delete(userID, id int64) (bool, error) {
+	result := r.db.Where("user_id = ?", userID).Delete(&model.APIToken{}, id)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *APITokenRepository) TouchLastUsed(id int64, at time.Time) error {
+	return r.db.Model(&model.APIToken{}).Where("id = ?", id).UpdateColumn("last_used_at", at).Error
+}
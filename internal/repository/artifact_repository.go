@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"gogo-scheduler/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type ArtifactRepository struct {
+	db *gorm.DB
+}
+
+func NewArtifactRepository(db *gorm.DB) *ArtifactRepository {
+	return &ArtifactRepository{db: db}
+}
+
+func (r *ArtifactRepository) Create(artifact *model.Artifact) error {
+	return r.db.Create(artifact).Error
+}
+
+func (r *ArtifactRepository) GetByID(taskID, id int64) (*model.Artifact, error) {
+	var artifact model.Artifact
+	err := r.db.Where("task_id = ?", taskID).First(&artifact, id).Error
+	return &artifact, err
+}
+
+func (r *ArtifactRepository) ListByTask(taskID int64) ([]model.Artifact, error) {
+	var artifacts []model.Artifact
+	err := r.db.Where("task_id = ?", taskID).Order("name").Find(&artifacts).Error
+	return artifacts, err
+}
+
+func (r *ArtifactRepository) ListByTasks(taskIDs []int64) ([]model.Artifact, error) {
+	var artifacts []model.Artifact
+	if len(taskIDs) == 0 {
+		return artifacts, nil
+	}
+	err := r.db.Where("task_id IN ?", taskIDs).Find(&artifacts).Error
+	return artifacts, err
+}
+
+func (r *ArtifactRepository) DeleteByTasks(taskIDs []int64) error {
+	if len(taskIDs) == 0 {
+		return nil
+	}
+	return r.db.Where("task_id IN ?", taskIDs).Delete(&model.Artifact{}).Error
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"gogo-scheduler/internal/model"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// auditExportBatchSize is the number of events read at a time by Export.
+const auditExportBatchSize = 500
+
+// AuditRepository stores audit events. It deliberately has no methods to
+// change or delete them.
+type AuditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+func (r *AuditRepository) Create(event *model.AuditEvent) error {
+	return r.db.Create(event).Error
+}
+
+// List returns a page of matching events, newest first, and the total
+// number of matches.
+func (r *AuditRepository) List(q model.AuditQuery) ([]model.AuditEvent, int64, error) {
+	query := r.filter(q)
+
+	var total int64
+	if err := query.Model(&model.AuditEvent{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var events []model.AuditEvent
+	page, pageSize := model.NormalizePage(q.Page, q.PageSize)
+	err := query.Order("id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&events).Error
+	return events, total, err
+}
+
+// Each calls fn for every matching event, oldest first, reading them in
+// batches.
+func (r *AuditRepository) Each(q model.AuditQuery, fn func(*model.AuditEvent) error) error {
+	var batch []model.AuditEvent
+	return r.filter(q).FindInBatches(&batch, auditExportBatchSize, func(tx *gorm.DB, _ int) error {
+		for i := range batch {
+			if err := fn(&batch[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
+
+func (r *AuditRepository) filter(q model.AuditQuery) *gorm.DB {
+	query := r.db.Model(&model.AuditEvent{})
+	if q.UserID != nil {
+		query = query.Where("user_id = ?", *q.UserID)
+	}
+	if q.Username != "" {
+		query = query.Where("username = ?", q.Username)
+	}
+	if strings.HasSuffix(q.Action, ".") {
+		query = query.Where(`action LIKE ? ESCAPE '\'`, escapeLike(q.Action)+"%")
+	} else if q.Action != "" {
+		query = query.Where("action = ?", q.Action)
+	}
+	if q.TargetType != "" {
+		query = query.Where("target_type = ?", q.TargetType)
+	}
+	if q.TargetID != "" {
+		query = query.Where("target_id = ?", q.TargetID)
+	}
+	if q.Since != nil {
+		query = query.Where("created_at >= ?", *q.Since)
+	}
+	if q.Until != nil {
+		query = query.Where("created_at < ?", *q.Until)
+	}
+	return query
+}
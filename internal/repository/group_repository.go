@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"gogo-scheduler/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type GroupRepository struct {
+	db *gorm.DB
+}
+
+func NewGroupRepository(db *gorm.DB) *GroupRepository {
+	return &GroupRepository{db: db}
+}
+
+// WithContext returns a repository that runs its queries with ctx.
+func (r *GroupRepository) WithContext(ctx context.Context) *GroupRepository {
+	return &GroupRepository{db: r.db.WithContext(ctx)}
+}
+
+// List returns all groups with their member IDs.
+func (r *GroupRepository) List() ([]model.Group, error) {
+	var groups []model.Group
+	if err := r.db.Order("name").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+
+	var members []model.GroupMember
+	if err := r.db.Order("user_id").Find(&members).Error; err != nil {
+		return nil, err
+	}
+	byGroup := make(map[int64][]int64)
+	for _, m := range members {
+		byGroup[m.GroupID] = append(byGroup[m.GroupID], m.UserID)
+	}
+	for i := range groups {
+		groups[i].MemberIDs = byGroup[groups[i].ID]
+	}
+	return groups, nil
+}
+
+func (r *GroupRepository) GetByID(id int64) (*model.Group, error) {
+	var group model.Group
+	if err := r.db.First(&group, id).Error; err != nil {
+		return nil, err
+	}
+	err := r.db.Model(&model.GroupMember{}).Where("group_id = ?", id).Order("user_id").Pluck("user_id", &group.MemberIDs).Error
+	return &group, err
+}
+
+func (r *GroupRepository) Create(group *model.Group) error {
+	return r.db.Create(group).Error
+}
+
+// Delete removes a group with its memberships and the scripts shared with it.
+func (r *GroupRepository) Delete(id int64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("group_id = ?", id).Delete(&model.GroupMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("group_id = ?", id).Delete(&model.ScriptShare{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Group{}, id).Error
+	})
+}
+
+func (r *GroupRepository) AddMember(groupID, userID int64) error {
+	return r.db.Save(&model.GroupMember{GroupID: groupID, UserID: userID}).Error
+}
+
+func (r *GroupRepository) RemoveMember(groupID, userID int64) error {
+	return r.db.Where("group_id = ? AND user_id = ?", groupID, userID).Delete(&model.GroupMember{}).Error
+}
+
+// GroupIDsOf returns the IDs of the groups the user belongs to.
+func (r *GroupRepository) GroupIDsOf(userID int64) ([]int64, error) {
+	var ids []int64
+	err := r.db.Model(&model.GroupMember{}).Where("user_id = ?", userID).Pluck("group_id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// HealthRepository checks the database the repositories use.
+type HealthRepository struct {
+	db *gorm.DB
+}
+
+func NewHealthRepository(db *gorm.DB) *HealthRepository {
+	return &HealthRepository{db: db}
+}
+
+func (r *HealthRepository) Ping(ctx context.Context) error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// MissingSchema returns the tables, and the columns as table.column, of
+// models that are not in the database.
+func (r *HealthRepository) MissingSchema(ctx context.Context, models ...any) ([]string, error) {
+	db := r.db.WithContext(ctx)
+	missing := []string{}
+	for _, m := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(m); err != nil {
+			return nil, err
+		}
+		table := stmt.Schema.Table
+		if !db.Migrator().HasTable(m) {
+			missing = append(missing, table)
+			continue
+		}
+
+		columns, err := db.Migrator().ColumnTypes(m)
+		if err != nil {
+			return nil, err
+		}
+		existing := make(map[string]bool, len(columns))
+		for _, column := range columns {
+			existing[column.Name()] = true
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" && !field.IgnoreMigration && !existing[field.DBName] {
+				missing = append(missing, table+"."+field.DBName)
+			}
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"log/slog"
+
+	"gogo-scheduler/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// NotificationRepository stores notification channels, the scripts
+// subscribed to them and the log of deliveries.
+type NotificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+func (r *NotificationRepository) ListChannels(projectID int64) ([]model.NotificationChannel, error) {
+	var channels []model.NotificationChannel
+	err := r.db.Where("project_id = ?", projectID).Order("name").Find(&channels).Error
+	return channels, err
+}
+
+func (r *NotificationRepository) GetChannel(id int64) (*model.NotificationChannel, error) {
+	var channel model.NotificationChannel
+	err := r.db.First(&channel, id).Error
+	return &channel, err
+}
+
+func (r *NotificationRepository) CreateChannel(channel *model.NotificationChannel) error {
+	return r.db.Create(channel).Error
+}
+
+func (r *NotificationRepository) UpdateChannel(channel *model.NotificationChannel) error {
+	return r.db.Save(channel).Error
+}
+
+// DeleteChannel removes a channel with its subscriptions and deliveries.
+func (r *NotificationRepository) DeleteChannel(id int64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("channel_id = ?", id).Delete(&model.ScriptNotification{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("channel_id = ?", id).Delete(&model.NotificationDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.NotificationChannel{}, id).Error
+	})
+}
+
+func (r *NotificationRepository) ListSubscriptions(scriptID int64) ([]model.ScriptNotification, error) {
+	var subscriptions []model.ScriptNotification
+	err := r.db.Where("script_id = ?", scriptID).Order("id").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// ReplaceSubscriptions replaces the subscriptions of a script.
+func (r *NotificationRepository) ReplaceSubscriptions(scriptID int64, subscriptions []model.ScriptNotification) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("script_id = ?", scriptID).Delete(&model.ScriptNotification{}).Error; err != nil {
+			return err
+		}
+		if len(subscriptions) == 0 {
+			return nil
+		}
+		return tx.Create(&subscriptions).Error
+	})
+}
+
+func (r *NotificationRepository) CreateDelivery(delivery *model.NotificationDelivery) error {
+	return r.db.Create(delivery).Error
+}
+
+func (r *NotificationRepository) UpdateDelivery(delivery *model.NotificationDelivery) error {
+	return r.db.Save(delivery).Error
+}
+
+// FailPendingDeliveries marks deliveries that were still being retried
+// when the service stopped as failed.
+func (r *NotificationRepository) FailPendingDeliveries() error {
+	result := r.db.Model(&model.NotificationDelivery{}).
+		Where("status = ?", "pending").
+		Updates(map[string]interface{}{"status": "failed", "error": "interrupted by a restart"})
+	if result.Error == nil && result.RowsAffected > 0 {
+		slog.Warn("marked notification deliveries interrupted by a restart as failed", "count", result.RowsAffected)
+	}
+	return result.Error
+}
+
+// ListDeliveries returns a page of a channel's deliveries, newest first.
+func (r *NotificationRepository) ListDeliveries(channelID int64, page, pageSize int) ([]model.NotificationDelivery, int64, error) {
+	var deliveries []model.NotificationDelivery
+	var total int64
+
+	query := r.db.Model(&model.NotificationDelivery{}).Where("channel_id = ?", channelID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page, pageSize = model.NormalizePage(page, pageSize)
+	err := query.Order("id desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&deliveries).Error
+	return deliveries, total, err
+}
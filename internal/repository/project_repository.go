@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"gogo-scheduler/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type ProjectRepository struct {
+	db *gorm.DB
+}
+
+func NewProjectRepository(db *gorm.DB) *ProjectRepository {
+	return &ProjectRepository{db: db}
+}
+
+// WithContext returns a repository that runs its queries with ctx.
+func (r *ProjectRepository) WithContext(ctx context.Context) *ProjectRepository {
+	return &ProjectRepository{db: r.db.WithContext(ctx)}
+}
+
+// CreateDefaultIfNotExists seeds the default project and moves scripts and
+// tasks from before projects into it.
+func (r *ProjectRepository) CreateDefaultIfNotExists() (*model.Project, error) {
+	project, err := r.GetBySlug(model.DefaultProjectSlug)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		slog.Info("creating the default project")
+		project = &model.Project{Slug: model.DefaultProjectSlug, Name: "Default"}
+		err = r.db.Create(project).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&model.Script{}).Where("project_id = 0").Update("project_id", project.ID).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&model.Task{}).Where("project_id = 0").Update("project_id", project.ID).Error
+	})
+	return project, err
+}
+
+func (r *ProjectRepository) List() ([]model.Project, error) {
+	var projects []model.Project
+	err := r.db.Order("slug").Find(&projects).Error
+	return projects, err
+}
+
+// ListByMember returns the projects the user is a member of.
+func (r *ProjectRepository) ListByMember(userID int64) ([]model.Project, error) {
+	var projects []model.Project
+	err := r.db.Where("id IN (SELECT project_id FROM project_members WHERE user_id = ?)", userID).
+		Order("slug").Find(&projects).Error
+	return projects, err
+}
+
+func (r *ProjectRepository) GetBySlug(slug string) (*model.Project, error) {
+	var project model.Project
+	err := r.db.Where("slug = ?", slug).First(&project).Error
+	return &project, err
+}
+
+func (r *ProjectRepository) GetByID(id int64) (*model.Project, error) {
+	var project model.Project
+	err := r.db.First(&project, id).Error
+	return &project, err
+}
+
+func (r *ProjectRepository) Create(project *model.Project) error {
+	return r.db.Create(project).Error
+}
+
+func (r *ProjectRepository) Update(project *model.Project) error {
+	return r.db.Save(project).Error
+}
+
+// Delete removes a project with its memberships and notification channels.
+// It fails if the project still has scripts.
+func (r *ProjectRepository) Delete(id int64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var scripts int64
+		if err := tx.Model(&model.Script{}).Where("project_id = ?", id).Count(&scripts).Error; err != nil {
+			return err
+		}
+		if scripts > 0 {
+			return errors.New("project still has scripts")
+		}
+		if err := tx.Where("project_id = ?", id).Delete(&model.ProjectMember{}).Error; err != nil {
+			return err
+		}
+		var channelIDs []int64
+		if err := tx.Model(&model.NotificationChannel{}).Where("project_id = ?", id).Pluck("id", &channelIDs).Error; err != nil {
+			return err
+		}
+		if len(channelIDs) > 0 {
+			if err := tx.Where("channel_id IN ?", channelIDs).Delete(&model.ScriptNotification{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("channel_id IN ?", channelIDs).Delete(&model.NotificationDelivery{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&model.NotificationChannel{}, channelIDs).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&model.Project{}, id).Error
+	})
+}
+
+// ListMembers returns the members of a project with their usernames.
+func (r *ProjectRepository) ListMembers(projectID int64) ([]model.ProjectMember, error) {
+	var members []model.ProjectMember
+	if err := r.db.Where("project_id = ?", projectID).Order("user_id").Find(&members).Error; err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]int64, len(members))
+	for i, member := range members {
+		userIDs[i] = member.UserID
+	}
+	var users []model.User
+	if err := r.db.Select("id", "username").Where("id IN ?", nonEmpty(userIDs)).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	usernames := make(map[int64]string, len(users))
+	for _, user := range users {
+		usernames[user.ID] = user.Username
+	}
+	for i := range members {
+		members[i].Username = usernames[members[i].UserID]
+	}
+	return members, nil
+}
+
+// GetMember returns the user's membership, or nil if they are not a member.
+func (r *ProjectRepository) GetMember(projectID, userID int64) (*model.ProjectMember, error) {
+	var member model.ProjectMember
+	err := r.db.Where("project_id = ? AND user_id = ?", projectID, userID).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// SaveMember adds a member or changes their role.
+func (r *ProjectRepository) SaveMember(member *model.ProjectMember) error {
+	return r.db.Save(member).Error
+}
+
+func (r *ProjectRepository) RemoveMember(projectID, userID int64) (bool, error) {
+	result := r.db.Where("project_id = ? AND user_id = ?", projectID, userID).Delete(&model.ProjectMember{})
+	return result.RowsAffected > 0, result.Error
+}
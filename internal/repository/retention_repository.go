@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"gogo-scheduler/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type RetentionRepository struct {
+	db *gorm.DB
+}
+
+func NewRetentionRepository(db *gorm.DB) *RetentionRepository {
+	return &RetentionRepository{db: db}
+}
+
+// GetByScriptID returns the policy for a script, or nil if none is set.
+// Pass 0 for the global policy.
+func (r *RetentionRepository) GetByScriptID(scriptID int64) (*model.RetentionPolicy, error) {
+	var policy model.RetentionPolicy
+	// Find instead of First: a missing policy is the common case and should
+	// not be logged as an error on every retention run
+	result := r.db.Where("script_id = ?", scriptID).Limit(1).Find(&policy)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &policy, nil
+}
+
+func (r *RetentionRepository) Save(policy *model.RetentionPolicy) error {
+	return r.db.Save(policy).Error
+}
+
+func (r *RetentionRepository) DeleteByScriptID(scriptID int64) error {
+	return r.db.Where("script_id = ?", scriptID).Delete(&model.RetentionPolicy{}).Error
+}
+
+// PurgeDeletedScripts hard-deletes soft-deleted scripts of a project, or of
+// any project for projectID 0, that no longer have any task rows referencing
+// them.
+func (r *RetentionRepository) PurgeDeletedScripts(projectID int64, dryRun bool) (int64, error) {
+	query := inProject(r.db.Unscoped().Model(&model.Script{}), "scripts.project_id", projectID).
+		Where("deleted_at IS NOT NULL").
+		Where("NOT EXISTS (SELECT 1 FROM tasks WHERE tasks.script_id = scripts.id AND tasks.deleted_at IS NULL)")
+	if dryRun {
+		var count int64
+		err := query.Count(&count).Error
+		return count, err
+	}
+	result := query.Delete(&model.Script{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *RetentionRepository) Vacuum() error {
+	return r.db.Exec("VACUUM").Error
+}
@@ -1,11 +1,18 @@
 package repository
 
 import (
+	"context"
+	"log/slog"
+	"strings"
+
 	"gogo-scheduler/internal/model"
 
 	"gorm.io/gorm"
 )
 
+// lastRunStatusExpr selects the status of the most recent task of a script.
+const lastRunStatusExpr = "(SELECT tasks.status FROM tasks WHERE tasks.script_id = scripts.id AND tasks.deleted_at IS NULL ORDER BY tasks.created_at DESC LIMIT 1)"
+
 type ScriptRepository struct {
 	db *gorm.DB
 }
@@ -14,6 +21,11 @@ func NewScriptRepository(db *gorm.DB) *ScriptRepository {
 	return &ScriptRepository{db: db}
 }
 
+// WithContext returns a repository that runs its queries with ctx.
+func (r *ScriptRepository) WithContext(ctx context.Context) *ScriptRepository {
+	return &ScriptRepository{db: r.db.WithContext(ctx)}
+}
+
 func (r *ScriptRepository) Create(script *model.Script) error {
 	return r.db.Create(script).Error
 }
@@ -24,10 +36,58 @@ func (r *ScriptRepository) GetByID(id int64) (*model.Script, error) {
 	return &script, err
 }
 
-func (r *ScriptRepository) List() ([]model.Script, error) {
+// GetByIDWithDeleted also finds deleted scripts.
+func (r *ScriptRepository) GetByIDWithDeleted(id int64) (*model.Script, error) {
+	var script model.Script
+	err := r.db.Unscoped().First(&script, id).Error
+	return &script, err
+}
+
+func (r *ScriptRepository) List(q model.ScriptQuery) ([]model.Script, int64, error) {
 	var scripts []model.Script
-	err := r.db.Find(&scripts).Error
-	return scripts, err
+	var total int64
+
+	query := r.db.Model(&model.Script{}).Where("scripts.project_id = ?", q.ProjectID)
+	if q.Search != "" {
+		like := "%" + escapeLike(q.Search) + "%"
+		query = query.Where(`scripts.name LIKE ? ESCAPE '\' OR scripts.description LIKE ? ESCAPE '\' OR scripts.content LIKE ? ESCAPE '\'`, like, like, like)
+	}
+	if q.Type != "" {
+		query = query.Where("scripts.type = ?", q.Type)
+	}
+	if q.Tag != "" {
+		// tags are stored as a JSON array, so match one of its elements
+		query = query.Where("EXISTS (SELECT 1 FROM json_each(scripts.tags) WHERE json_each.value = ?)", q.Tag)
+	}
+	query = readableScripts(query, "scripts.id", q.Reader)
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page, pageSize := model.NormalizePage(q.Page, q.PageSize)
+	err := query.Order(scriptOrder(q.Sort, q.Order)).
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&scripts).Error
+	return scripts, total, err
+}
+
+// AssignOwnerless gives scripts from before ownership an owner: the user
+// who created them according to the audit log, or else the first
+// administrator.
+func (r *ScriptRepository) AssignOwnerless() error {
+	creator := "SELECT audit_events.user_id FROM audit_events JOIN users ON users.id = audit_events.user_id " +
+		"WHERE audit_events.action = ? AND audit_events.success AND audit_events.target_type = 'script' " +
+		"AND audit_events.target_id = CAST(scripts.id AS TEXT) ORDER BY audit_events.id LIMIT 1"
+	admin := "SELECT id FROM users WHERE role = ? ORDER BY id LIMIT 1"
+	result := r.db.Unscoped().Model(&model.Script{}).
+		Where("owner_id = 0").
+		Update("owner_id", gorm.Expr("COALESCE(("+creator+"), ("+admin+"), 0)", model.AuditScriptCreate, model.RoleAdmin))
+	if result.Error == nil && result.RowsAffected > 0 {
+		slog.Info("assigned owners to scripts from before ownership", "count", result.RowsAffected)
+	}
+	return result.Error
 }
 
 func (r *ScriptRepository) Update(script *model.Script) error {
@@ -37,3 +97,27 @@ func (r *ScriptRepository) Update(script *model.Script) error {
 func (r *ScriptRepository) Delete(id int64) error {
 	return r.db.Delete(&model.Script{}, id).Error
 }
+
+// escapeLike escapes the LIKE wildcards in s, for patterns that use
+// ESCAPE '\'.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func scriptOrder(sort, order string) string {
+	dir := "ASC"
+	if strings.EqualFold(order, "desc") {
+		dir = "DESC"
+	}
+
+	switch sort {
+	case "name":
+		return "scripts.name " + dir
+	case "last_run_status":
+		return lastRunStatusExpr + " " + dir + ", scripts.id"
+	case "updated_at":
+		return "scripts.updated_at " + dir
+	default:
+		return "scripts.id " + dir
+	}
+}
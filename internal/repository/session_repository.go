@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"gogo-scheduler/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SessionRepository stores refresh tokens and the access token denylist.
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+func (r *SessionRepository) CreateRefreshToken(token *model.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *SessionRepository) FindRefreshToken(hash string) (*model.RefreshToken, error) {
+	var token model.RefreshToken
+	err := r.db.Where("token_hash = ?", hash).First(&token).Error
+	return &token, err
+}
+
+// RevokeRefreshToken revokes a token and reports whether this call did so,
+// so that only one of two concurrent refreshes with the same token succeeds.
+func (r *SessionRepository) RevokeRefreshToken(id int64) (bool, error) {
+	result := r.db.Model(&model.RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *SessionRepository) RevokeFamily(familyID string) error {
+	return r.db.Model(&model.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// DenyAccessToken denylists an access token until it expires. Expired
+// entries are removed at the same time.
+func (r *SessionRepository) DenyAccessToken(jti string, expiresAt time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at < ?", time.Now()).Delete(&model.RevokedAccessToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Save(&model.RevokedAccessToken{JTI: jti, ExpiresAt: expiresAt}).Error
+	})
+}
+
+func (r *SessionRepository) IsAccessTokenDenied(jti string) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.RevokedAccessToken{}).Where("jti = ?", jti).Count(&count).Error
+	return count > 0, err
+}
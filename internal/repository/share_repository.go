@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"gogo-scheduler/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// ShareRepository stores the grants that share scripts with users and
+// groups.
+type ShareRepository struct {
+	db *gorm.DB
+}
+
+func NewShareRepository(db *gorm.DB) *ShareRepository {
+	return &ShareRepository{db: db}
+}
+
+// WithContext returns a repository that runs its queries with ctx.
+func (r *ShareRepository) WithContext(ctx context.Context) *ShareRepository {
+	return &ShareRepository{db: r.db.WithContext(ctx)}
+}
+
+func (r *ShareRepository) ListByScript(scriptID int64) ([]model.ScriptShare, error) {
+	var shares []model.ScriptShare
+	err := r.db.Where("script_id = ?", scriptID).Order("id").Find(&shares).Error
+	return shares, err
+}
+
+// ListFor returns the shares of a script that apply to the user, directly
+// or through one of the groups.
+func (r *ShareRepository) ListFor(scriptID int64, reader model.ScriptReader) ([]model.ScriptShare, error) {
+	var shares []model.ScriptShare
+	err := r.db.Where("script_id = ?", scriptID).
+		Where("user_id = ? OR group_id IN ?", reader.UserID, nonEmpty(reader.GroupIDs)).
+		Find(&shares).Error
+	return shares, err
+}
+
+// Save creates a share, or updates the level of an existing share with the
+// same script and user or group.
+func (r *ShareRepository) Save(share *model.ScriptShare) error {
+	var existing model.ScriptShare
+	query := r.db.Where("script_id = ?", share.ScriptID)
+	if share.UserID != nil {
+		query = query.Where("user_id = ?", *share.UserID)
+	} else {
+		query = query.Where("group_id = ?", *share.GroupID)
+	}
+	err := query.Limit(1).Find(&existing).Error
+	if err != nil {
+		return err
+	}
+	share.ID = existing.ID
+	if existing.ID != 0 {
+		share.CreatedAt = existing.CreatedAt
+	}
+	return r.db.Save(share).Error
+}
+
+// Delete removes a share of the script and reports whether it existed.
+func (r *ShareRepository) Delete(scriptID, id int64) (bool, error) {
+	result := r.db.Where("script_id = ?", scriptID).Delete(&model.ScriptShare{}, id)
+	return result.RowsAffected > 0, result.Error
+}
+
+// readableScripts restricts a query on scripts to those the reader owns or
+// that are shared with the reader.
+func readableScripts(query *gorm.DB, column string, reader *model.ScriptReader) *gorm.DB {
+	if reader == nil {
+		return query
+	}
+	return query.Where(column+" IN (SELECT id FROM scripts WHERE owner_id = ?) OR "+
+		column+" IN (SELECT script_id FROM script_shares WHERE user_id = ? OR group_id IN ?)",
+		reader.UserID, reader.UserID, nonEmpty(reader.GroupIDs))
+}
+
+// nonEmpty avoids an empty IN () list, which is a syntax error in some
+// databases.
+func nonEmpty(ids []int64) []int64 {
+	if len(ids) == 0 {
+		return []int64{0}
+	}
+	return ids
+}
@@ -1,6 +1,10 @@
 package repository
 
 import (
+	"context"
+	"log/slog"
+	"time"
+
 	"gogo-scheduler/internal/model"
 
 	"gorm.io/gorm"
@@ -14,6 +18,11 @@ func NewTaskRepository(db *gorm.DB) *TaskRepository {
 	return &TaskRepository{db: db}
 }
 
+// WithContext returns a repository that runs its queries with ctx.
+func (r *TaskRepository) WithContext(ctx context.Context) *TaskRepository {
+	return &TaskRepository{db: r.db.WithContext(ctx)}
+}
+
 func (r *TaskRepository) Create(task *model.Task) error {
 	return r.db.Create(task).Error
 }
@@ -24,14 +33,31 @@ func (r *TaskRepository) GetByID(id int64) (*model.Task, error) {
 	return &task, err
 }
 
-func (r *TaskRepository) List(scriptID *int64) ([]model.Task, error) {
+// List returns a page of the tasks of a script, or of all scripts of the
+// project if scriptID is nil, limited to the scripts the reader can read
+// unless reader is nil, and the total number of them. The content of the
+// scripts is left out.
+func (r *TaskRepository) List(projectID int64, scriptID *int64, reader *model.ScriptReader, page, pageSize int) ([]model.Task, int64, error) {
 	var tasks []model.Task
-	query := r.db.Preload("Script")
+	var total int64
+
+	query := r.db.Model(&model.Task{}).Where("tasks.project_id = ?", projectID)
 	if scriptID != nil {
 		query = query.Where("script_id = ?", *scriptID)
 	}
-	err := query.Order("created_at desc").Find(&tasks).Error
-	return tasks, err
+	query = readableScripts(query, "tasks.script_id", reader)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page, pageSize = model.NormalizePage(page, pageSize)
+	err := query.Preload("Script", func(db *gorm.DB) *gorm.DB { return db.Omit("content") }).
+		Omit("script_content").
+		Order("created_at desc").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&tasks).Error
+	return tasks, total, err
 }
 
 func (r *TaskRepository) Update(task *model.Task) error {
@@ -41,3 +67,93 @@ func (r *TaskRepository) Update(task *model.Task) error {
 func (r *TaskRepository) Delete(id int64) error {
 	return r.db.Delete(&model.Task{}, id).Error
 }
+
+// CountActive counts the pending and running tasks of a project.
+func (r *TaskRepository) CountActive(projectID int64) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Task{}).
+		Where("project_id = ? AND status IN ?", projectID, []string{"pending", "running"}).
+		Count(&count).Error
+	return count, err
+}
+
+// FailInterrupted marks tasks that were still pending or running when the
+// service stopped as failed.
+func (r *TaskRepository) FailInterrupted() error {
+	result := r.db.Model(&model.Task{}).
+		Where("status IN ?", []string{"pending", "running"}).
+		Updates(map[string]interface{}{"status": "failed", "error": "interrupted by a restart"})
+	if result.Error == nil && result.RowsAffected > 0 {
+		slog.Warn("marked tasks interrupted by a restart as failed", "count", result.RowsAffected)
+	}
+	return result.Error
+}
+
+// Decide stores the decision on a task awaiting approval. It reports false
+// if the task was no longer awaiting approval.
+func (r *TaskRepository) Decide(task *model.Task) (bool, error) {
+	result := r.db.Model(&model.Task{}).
+		Where("id = ? AND status = ?", task.ID, "awaiting_approval").
+		Updates(map[string]interface{}{
+			"status":           task.Status,
+			"decided_by":       task.DecidedBy,
+			"decided_at":       task.DecidedAt,
+			"decision_comment": task.DecisionComment,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ExpireApprovals marks tasks whose approval request has expired and
+// returns how many there were.
+func (r *TaskRepository) ExpireApprovals(now time.Time) (int64, error) {
+	result := r.db.Model(&model.Task{}).
+		Where("status = ? AND approval_expires_at < ?", "awaiting_approval", now).
+		Update("status", "expired")
+	return result.RowsAffected, result.Error
+}
+
+// ScriptIDs returns the distinct script IDs that still have tasks in a
+// project, or in any project for projectID 0.
+func (r *TaskRepository) ScriptIDs(projectID int64) ([]int64, error) {
+	var ids []int64
+	err := inProject(r.db.Model(&model.Task{}), "project_id", projectID).Distinct().Pluck("script_id", &ids).Error
+	return ids, err
+}
+
+// ListFinished returns the finished tasks of a script, newest first, without
+// loading output or the script association.
+func (r *TaskRepository) ListFinished(scriptID int64) ([]model.Task, error) {
+	var tasks []model.Task
+	err := r.db.Select("id", "script_id", "status", "created_at").
+		Where("script_id = ?", scriptID).
+		Where("status NOT IN ?", []string{"awaiting_approval", "pending", "running"}).
+		Order("created_at desc").
+		Find(&tasks).Error
+	return tasks, err
+}
+
+// HardDelete removes tasks permanently, bypassing soft delete.
+func (r *TaskRepository) HardDelete(ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Unscoped().Delete(&model.Task{}, ids).Error
+}
+
+// DeletedIDs returns the IDs of soft-deleted tasks in a project, or in any
+// project for projectID 0.
+func (r *TaskRepository) DeletedIDs(projectID int64) ([]int64, error) {
+	var ids []int64
+	query := inProject(r.db.Unscoped().Model(&model.Task{}), "project_id", projectID)
+	err := query.Where("deleted_at IS NOT NULL").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// inProject limits query to the rows whose column holds projectID, unless
+// projectID is 0.
+func inProject(query *gorm.DB, column string, projectID int64) *gorm.DB {
+	if projectID == 0 {
+		return query
+	}
+	return query.Where(column+" = ?", projectID)
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"gogo-scheduler/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TwoFactorRepository stores TOTP secrets and recovery codes.
+type TwoFactorRepository struct {
+	db *gorm.DB
+}
+
+func NewTwoFactorRepository(db *gorm.DB) *TwoFactorRepository {
+	return &TwoFactorRepository{db: db}
+}
+
+// SetSecret stores a new, not yet enabled TOTP secret.
+func (r *TwoFactorRepository) SetSecret(userID int64, secret string) error {
+	return r.db.Model(&model.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"totp_secret":    secret,
+		"totp_enabled":   false,
+		"totp_last_step": 0,
+	}).Error
+}
+
+// Enable turns on two-factor authentication and replaces the recovery
+// codes. lastStep is the step of the code that confirmed the enrollment.
+func (r *TwoFactorRepository) Enable(userID int64, lastStep int64, codeHashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"totp_enabled":   true,
+			"totp_last_step": lastStep,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+func (r *TwoFactorRepository) Disable(userID int64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"totp_secret":    "",
+			"totp_enabled":   false,
+			"totp_last_step": 0,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error
+	})
+}
+
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(userID int64, codeHashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID int64, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	codes := make([]model.RecoveryCode, len(codeHashes))
+	for i, hash := range codeHashes {
+		codes[i] = model.RecoveryCode{UserID: userID, CodeHash: hash}
+	}
+	return tx.Create(&codes).Error
+}
+
+// UseStep records a TOTP time step as used and reports whether it was
+// newer than the last one, so that each code is accepted only once.
+func (r *TwoFactorRepository) UseStep(userID, step int64) (bool, error) {
+	result := r.db.Model(&model.User{}).
+		Where("id = ? AND totp_last_step < ?", userID, step).
+		UpdateColumn("totp_last_step", step)
+	return result.RowsAffected > 0, result.Error
+}
+
+// UseRecoveryCode marks a recovery code as used and reports whether it was
+// valid and unused.
+func (r *TwoFactorRepository) UseRecoveryCode(userID int64, codeHash string) (bool, error) {
+	result := r.db.Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetSecuritySettings returns the instance settings, or the defaults if
+// they were never saved.
+func (r *TwoFactorRepository) GetSecuritySettings() (*model.SecuritySettings, error) {
+	var settings model.SecuritySettings
+	err := r.db.Limit(1).Find(&settings, 1).Error
+	settings.ID = 1
+	return &settings, err
+}
+
+func (r *TwoFactorRepository) SaveSecuritySettings(settings *model.SecuritySettings) error {
+	settings.ID = 1
+	return r.db.Save(settings).Error
+}
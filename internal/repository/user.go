@@ -1,8 +1,11 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"gogo-scheduler/internal/model"
+	"log/slog"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -15,6 +18,11 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+// WithContext returns a repository that runs its queries with ctx.
+func (r *UserRepository) WithContext(ctx context.Context) *UserRepository {
+	return &UserRepository{db: r.db.WithContext(ctx)}
+}
+
 func (r *UserRepository) Create(user *model.User) error {
 	return r.db.Create(user).Error
 }
@@ -31,6 +39,18 @@ func (r *UserRepository) FindByUsername(username string) (*model.User, error) {
 	return &user, nil
 }
 
+func (r *UserRepository) FindByExternalID(provider, externalID string) (*model.User, error) {
+	var user model.User
+	err := r.db.Where("auth_provider = ? AND external_id = ?", provider, externalID).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
 func (r *UserRepository) FindByID(id uint) (*model.User, error) {
 	var user model.User
 	err := r.db.First(&user, id).Error
@@ -40,22 +60,122 @@ func (r *UserRepository) FindByID(id uint) (*model.User, error) {
 	return &user, nil
 }
 
+func (r *UserRepository) List() ([]model.User, error) {
+	var users []model.User
+	err := r.db.Order("id").Find(&users).Error
+	return users, err
+}
+
+func (r *UserRepository) UpdateRole(id int64, role model.Role) error {
+	return r.db.Model(&model.User{}).Where("id = ?", id).Update("role", role).Error
+}
+
+func (r *UserRepository) SetDisabled(id int64, disabled bool) error {
+	return r.db.Model(&model.User{}).Where("id = ?", id).Update("disabled", disabled).Error
+}
+
+// SetPassword stores an already hashed password.
+func (r *UserRepository) SetPassword(id int64, hashedPassword string) error {
+	return r.db.Model(&model.User{}).Where("id = ?", id).Update("password", hashedPassword).Error
+}
+
+// Delete removes a user together with their API tokens and sessions, so
+// they can never authenticate a later user that reuses the ID. Their
+// scripts are given to heirID and they are removed from the approvers of
+// scripts.
+func (r *UserRepository) Delete(id, heirID int64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Model(&model.Script{}).Where("owner_id = ?", id).Update("owner_id", heirID).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Unscoped().Model(&model.Script{}).
+			Where("EXISTS (SELECT 1 FROM json_each(scripts.approvers) WHERE json_each.value = ?)", id).
+			Update("approvers", gorm.Expr("(SELECT json_group_array(json_each.value) FROM json_each(scripts.approvers) WHERE json_each.value <> ?)", id)).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&model.APIToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&model.RefreshToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&model.GroupMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&model.ScriptShare{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&model.ProjectMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.User{}, id).Error
+	})
+}
+
+// InvalidateSessions logs a user out everywhere: access tokens stop
+// validating because the token version changes, and refresh tokens are
+// revoked.
+func (r *UserRepository) InvalidateSessions(id int64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.User{}).Where("id = ?", id).
+			UpdateColumn("token_version", gorm.Expr("token_version + 1")).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&model.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", id).
+			Update("revoked_at", time.Now()).Error
+	})
+}
+
+// CountByRole counts the enabled users with the role.
+func (r *UserRepository) CountByRole(role model.Role) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.User{}).Where("role = ? AND disabled = ?", role, false).Count(&count).Error
+	return count, err
+}
+
+// CreateAdminIfNotExists seeds the admin account. If the account exists but
+// nobody holds the admin role (e.g. after roles were introduced), it is
+// promoted so the instance always has an administrator. The admin has to
+// change the default password before doing anything else.
 func (r *UserRepository) CreateAdminIfNotExists() error {
 	admin, err := r.FindByUsername("admin")
 	if err != nil {
 		return err
 	}
 	if admin != nil {
-		return nil
+		if !admin.MustChangePassword && admin.CheckPassword("admin") {
+			// installations from before forced password changes
+			err := r.db.Model(admin).Update("must_change_password", true).Error
+			if err != nil {
+				return err
+			}
+		}
+		admins, err := r.CountByRole(model.RoleAdmin)
+		if err != nil || admins > 0 {
+			return err
+		}
+		slog.Warn("no administrators left; making the admin user one")
+		return r.UpdateRole(admin.ID, model.RoleAdmin)
 	}
 	admin = &model.User{
-		Username: "admin",
-		Password: "admin",
+		Username:           "admin",
+		Password:           "admin",
+		Role:               model.RoleAdmin,
+		AuthProvider:       model.AuthProviderLocal,
+		MustChangePassword: true,
 	}
 	err = admin.HashPassword()
 	if err != nil {
 		return err
 	}
+	slog.Info("creating the admin user with the default password, which must be changed at the first login")
 	return r.Create(admin)
 }
 
@@ -69,5 +189,6 @@ func (r *UserRepository) ChangePassword(username, password string) error {
 	if err != nil {
 		return err
 	}
+	user.MustChangePassword = false
 	return r.db.Save(user).Error
 }
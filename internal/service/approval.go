@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/tracing"
+)
+
+var (
+	ErrNotAwaitingApproval = errors.New("task is not awaiting approval")
+	ErrApprovalExpired     = errors.New("approval request has expired")
+	ErrSelfApproval        = errors.New("runs must be approved by someone other than who started them")
+	ErrApproverNotFound    = errors.New("approver not found")
+)
+
+// ApproveTask releases a run that is awaiting approval. It still counts
+// against the project's limit of concurrent runs.
+func (s *ScriptService) ApproveTask(ctx context.Context, scope model.ProjectScope, taskID int64, comment string) (*model.Task, error) {
+	ctx, span := tracing.Start(ctx, "ScriptService.ApproveTask")
+	defer span.End()
+
+	task, err := s.approvalTask(ctx, scope, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	recordDecision(task, scope.User, "running", comment)
+	err = s.admit(ctx, task.ProjectID, func() error {
+		return s.decide(ctx, task)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return task, s.submit(ctx, task)
+}
+
+// RejectTask cancels a run that is awaiting approval.
+func (s *ScriptService) RejectTask(ctx context.Context, scope model.ProjectScope, taskID int64, comment string) (*model.Task, error) {
+	ctx, span := tracing.Start(ctx, "ScriptService.RejectTask")
+	defer span.End()
+
+	task, err := s.approvalTask(ctx, scope, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	recordDecision(task, scope.User, "rejected", comment)
+	if err := s.decide(ctx, task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// approvalTask returns the task if it is awaiting approval and the user may
+// decide on it: someone other than who started the run, who is one of the
+// script's approvers or, without approvers, can edit the script.
+func (s *ScriptService) approvalTask(ctx context.Context, scope model.ProjectScope, taskID int64) (*model.Task, error) {
+	task, err := s.task(ctx, scope, taskID, model.PermissionRead)
+	if err != nil {
+		return nil, err
+	}
+	if task.Status != "awaiting_approval" {
+		return nil, ErrNotAwaitingApproval
+	}
+	if task.ApprovalExpiresAt != nil && time.Now().After(*task.ApprovalExpiresAt) {
+		if _, err := s.taskRepo.WithContext(ctx).ExpireApprovals(time.Now()); err != nil {
+			return nil, err
+		}
+		return nil, ErrApprovalExpired
+	}
+	if task.RequestedBy == scope.User.ID {
+		return nil, ErrSelfApproval
+	}
+
+	script, err := s.script(ctx, scope, task.ScriptID, model.PermissionRead)
+	if err != nil {
+		return nil, err
+	}
+	if len(script.Approvers) > 0 {
+		if !slices.Contains(script.Approvers, scope.User.ID) {
+			return nil, ErrAccessDenied
+		}
+		return task, nil
+	}
+	if err := s.authorize(ctx, scope.User, script, model.PermissionEdit); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+func recordDecision(task *model.Task, user *model.User, status, comment string) {
+	now := time.Now()
+	task.Status = status
+	task.DecidedBy = &user.ID
+	task.DecidedAt = &now
+	task.DecisionComment = comment
+}
+
+// decide stores the decision, failing if another one was made first.
+func (s *ScriptService) decide(ctx context.Context, task *model.Task) error {
+	ok, err := s.taskRepo.WithContext(ctx).Decide(task)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotAwaitingApproval
+	}
+	return nil
+}
+
+// validateApprovers checks that the approvers of a script exist.
+func (s *ScriptService) validateApprovers(ctx context.Context, ids []int64) error {
+	for _, id := range ids {
+		if _, err := s.userRepo.WithContext(ctx).FindByID(uint(id)); err != nil {
+			return fmt.Errorf("%w: %d", ErrApproverNotFound, id)
+		}
+	}
+	return nil
+}
+
+// StartApprovalExpiry expires stale approval requests every interval until
+// ctx is done.
+func (s *ScriptService) StartApprovalExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.expiryLoop.beat()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.expiryLoop.beat()
+			expired, err := s.taskRepo.WithContext(ctx).ExpireApprovals(time.Now())
+			if err != nil {
+				slog.ErrorContext(ctx, "expiring approval requests failed", "error", err)
+				continue
+			}
+			if expired > 0 {
+				slog.InfoContext(ctx, "expired approval requests", "count", expired)
+			}
+		}
+	}
+}
+
+// LastApprovalExpiry returns when StartApprovalExpiry last ran, or the zero
+// time if it is not running.
+func (s *ScriptService) LastApprovalExpiry() time.Time {
+	return s.expiryLoop.Last()
+}
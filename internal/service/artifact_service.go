@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/repository"
+	"gogo-scheduler/internal/storage"
+)
+
+// ArtifactService collects files that scripts leave in their workspace and
+// keeps them in a storage.Store.
+type ArtifactService struct {
+	repo         *repository.ArtifactRepository
+	store        storage.Store
+	maxFileSize  int64
+	maxTotalSize int64
+}
+
+// NewArtifactService creates an ArtifactService. Files above maxFileSize
+// bytes, and files that would push a task over maxTotalSize bytes, are
+// skipped; 0 disables either limit.
+func NewArtifactService(repo *repository.ArtifactRepository, store storage.Store, maxFileSize, maxTotalSize int64) *ArtifactService {
+	return &ArtifactService{repo: repo, store: store, maxFileSize: maxFileSize, maxTotalSize: maxTotalSize}
+}
+
+// ValidatePatterns checks that artifact patterns stay inside the workspace.
+func ValidatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if pattern == "" || path.IsAbs(pattern) || filepath.IsAbs(pattern) {
+			return fmt.Errorf("invalid artifact pattern %q: must be a relative path", pattern)
+		}
+		for _, segment := range strings.Split(pattern, "/") {
+			if segment == ".." {
+				return fmt.Errorf("invalid artifact pattern %q: must not leave the workspace", pattern)
+			}
+			if _, err := path.Match(segment, ""); err != nil {
+				return fmt.Errorf("invalid artifact pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Collect stores the regular files in workspace matching any of the patterns
+// as artifacts of the task. Patterns are slash-separated globs relative to
+// the workspace; "**" matches any number of directories and a pattern that
+// matches a directory collects everything below it.
+func (s *ArtifactService) Collect(ctx context.Context, task *model.Task, workspace string, patterns []string) error {
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	var total int64
+	return filepath.WalkDir(workspace, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Symlinks are skipped so artifacts cannot point outside the workspace
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(workspace, name)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !matchAny(patterns, rel) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if s.maxFileSize > 0 && info.Size() > s.maxFileSize {
+			slog.WarnContext(ctx, "skipping artifact over the size limit", "artifact", rel, "size", info.Size(), "limit", s.maxFileSize)
+			return nil
+		}
+		if s.maxTotalSize > 0 && total+info.Size() > s.maxTotalSize {
+			slog.WarnContext(ctx, "skipping artifact over the total size limit", "artifact", rel, "size", info.Size(), "limit", s.maxTotalSize)
+			return nil
+		}
+
+		if err := s.save(ctx, task.ID, rel, name, info.Size()); err != nil {
+			return err
+		}
+		total += info.Size()
+		return nil
+	})
+}
+
+func (s *ArtifactService) List(taskID int64) ([]model.Artifact, error) {
+	return s.repo.ListByTask(taskID)
+}
+
+// Open returns an artifact and a reader for its content. The caller closes
+// the reader.
+func (s *ArtifactService) Open(ctx context.Context, taskID, id int64) (*model.Artifact, io.ReadCloser, error) {
+	artifact, err := s.repo.GetByID(taskID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	rc, err := s.store.Get(ctx, artifact.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return artifact, rc, nil
+}
+
+// Delete removes all artifacts of the given tasks.
+func (s *ArtifactService) Delete(ctx context.Context, taskIDs ...int64) error {
+	artifacts, err := s.repo.ListByTasks(taskIDs)
+	if err != nil {
+		return err
+	}
+	for _, artifact := range artifacts {
+		if err := s.store.Delete(ctx, artifact.StorageKey); err != nil {
+			return err
+		}
+	}
+	return s.repo.DeleteByTasks(taskIDs)
+}
+
+func (s *ArtifactService) save(ctx context.Context, taskID int64, rel, name string, size int64) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	key := fmt.Sprintf("tasks/%d/artifacts/%s", taskID, rel)
+	if err := s.store.Put(ctx, key, f, size); err != nil {
+		return err
+	}
+	return s.repo.Create(&model.Artifact{
+		TaskID:     taskID,
+		Name:       rel,
+		Size:       size,
+		StorageKey: key,
+	})
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(strings.Split(strings.Trim(pattern, "/"), "/"), strings.Split(name, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches path segments against pattern segments. "**" matches
+// zero or more segments, and a pattern that runs out before the path matches
+// everything below it.
+func matchGlob(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return true
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchGlob(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	ok, err := path.Match(pattern[0], name[0])
+	if err != nil || !ok {
+		return false
+	}
+	return matchGlob(pattern[1:], name[1:])
+}
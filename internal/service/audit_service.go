@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"reflect"
+	"sort"
+
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/repository"
+)
+
+// auditIgnoredFields change on every update and are left out of diffs.
+var auditIgnoredFields = map[string]bool{"updated_at": true}
+
+type AuditService struct {
+	repo *repository.AuditRepository
+}
+
+func NewAuditService(repo *repository.AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// Record stores an event with the fields that differ between before and
+// after, either of which may be nil. Failing to write the audit log is
+// logged but does not fail the action being audited.
+func (s *AuditService) Record(ctx context.Context, event *model.AuditEvent, before, after interface{}) {
+	changes, err := auditChanges(before, after)
+	if err != nil {
+		slog.WarnContext(ctx, "diffing audit event failed", "action", event.Action, "error", err)
+	}
+	event.Changes = changes
+	if err := s.repo.Create(event); err != nil {
+		slog.ErrorContext(ctx, "recording audit event failed", "action", event.Action, "error", err)
+	}
+}
+
+func (s *AuditService) List(q model.AuditQuery) (*model.PageResult[model.AuditEvent], error) {
+	events, total, err := s.repo.List(q)
+	if err != nil {
+		return nil, err
+	}
+	return &model.PageResult[model.AuditEvent]{
+		Items:    events,
+		Total:    total,
+		Page:     q.Page,
+		PageSize: q.PageSize,
+	}, nil
+}
+
+// Export writes the matching events to w as JSON lines, oldest first.
+func (s *AuditService) Export(w io.Writer, q model.AuditQuery) error {
+	enc := json.NewEncoder(w)
+	return s.repo.Each(q, func(event *model.AuditEvent) error {
+		return enc.Encode(event)
+	})
+}
+
+// auditChanges compares the JSON representations of before and after, so
+// fields hidden from the API never end up in the audit log.
+func auditChanges(before, after interface{}) ([]model.AuditChange, error) {
+	if before == nil && after == nil {
+		return nil, nil
+	}
+	old, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []model.AuditChange
+	for field, value := range updated {
+		if auditIgnoredFields[field] || reflect.DeepEqual(old[field], value) {
+			continue
+		}
+		changes = append(changes, model.AuditChange{Field: field, Before: old[field], After: value})
+	}
+	for field, value := range old {
+		if _, ok := updated[field]; !ok && value != nil && !auditIgnoredFields[field] {
+			changes = append(changes, model.AuditChange{Field: field, Before: value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
+
+func jsonFields(v interface{}) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	if v == nil || reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil() {
+		return fields, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
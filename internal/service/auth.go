@@ -1,7 +1,14 @@
 package service
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
 	"time"
 
 	"gogo-scheduler/internal/model"
@@ -10,38 +17,213 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// apiTokenTouchInterval limits how often LastUsedAt is written for a token
+// that is used on every request.
+const apiTokenTouchInterval = time.Minute
+
+type AuthConfig struct {
+	JWTSecret       string
+	AccessTokenTTL  time.Duration // 15 minutes if zero
+	RefreshTokenTTL time.Duration // 7 days if zero
+	PasswordPolicy  PasswordPolicy
+	LoginThrottle   ThrottleConfig
+}
+
 type AuthService struct {
-	userRepo    *repository.UserRepository
-	jwtSecret   []byte
-	tokenExpiry time.Duration
+	userRepo           *repository.UserRepository
+	tokenRepo          *repository.APITokenRepository
+	sessionRepo        *repository.SessionRepository
+	twoFactorRepo      *repository.TwoFactorRepository
+	authenticators     []Authenticator // tried in order by Login
+	throttle           *LoginThrottle
+	passwordPolicy     PasswordPolicy
+	jwtSecret          []byte
+	tokenExpiry        time.Duration // access token lifetime
+	refreshTokenExpiry time.Duration
+
+	mu         sync.Mutex
+	challenges map[string]*twoFactorChallenge // pending two-factor logins
+	settings   *model.SecuritySettings
 }
 
-func NewAuthService(userRepo *repository.UserRepository, jwtSecret string) *AuthService {
+// NewAuthService creates the auth service. Login tries the authenticators
+// in order and falls back to local passwords only if none are given.
+func NewAuthService(userRepo *repository.UserRepository, tokenRepo *repository.APITokenRepository, sessionRepo *repository.SessionRepository, twoFactorRepo *repository.TwoFactorRepository, cfg AuthConfig, authenticators ...Authenticator) *AuthService {
+	if len(authenticators) == 0 {
+		authenticators = []Authenticator{NewLocalAuthenticator(userRepo)}
+	}
+	if cfg.AccessTokenTTL == 0 {
+		cfg.AccessTokenTTL = 15 * time.Minute
+	}
+	if cfg.RefreshTokenTTL == 0 {
+		cfg.RefreshTokenTTL = 7 * 24 * time.Hour
+	}
 	return &AuthService{
-		userRepo:    userRepo,
-		jwtSecret:   []byte(jwtSecret),
-		tokenExpiry: 24 * time.Hour,
+		userRepo:           userRepo,
+		tokenRepo:          tokenRepo,
+		sessionRepo:        sessionRepo,
+		twoFactorRepo:      twoFactorRepo,
+		authenticators:     authenticators,
+		throttle:           NewLoginThrottle(cfg.LoginThrottle),
+		passwordPolicy:     cfg.PasswordPolicy,
+		jwtSecret:          []byte(cfg.JWTSecret),
+		tokenExpiry:        cfg.AccessTokenTTL,
+		refreshTokenExpiry: cfg.RefreshTokenTTL,
+		challenges:         make(map[string]*twoFactorChallenge),
 	}
 }
 
-func (s *AuthService) Login(username, password string) (*model.LoginResponse, error) {
-	user, err := s.userRepo.FindByUsername(username)
+// Login authenticates a user. ip is the client address, used together with
+// the username to slow down and lock out password guessing.
+func (s *AuthService) Login(username, password, ip string) (*model.LoginResponse, error) {
+	if err := s.throttle.Check(username, ip); err != nil {
+		return nil, err
+	}
+
+	var user *model.User
+	for _, authenticator := range s.authenticators {
+		u, err := authenticator.Authenticate(username, password)
+		if err == nil {
+			user = u
+			break
+		}
+		// an unreachable backend must not lock out users of the others
+		if !errors.Is(err, ErrInvalidCredentials) {
+			slog.Warn("authentication backend failed", "authenticator", authenticator.Name(), "error", err)
+		}
+	}
+	if user == nil {
+		s.throttle.Fail(username, ip)
+		return nil, ErrInvalidCredentials
+	}
+
+	if user.Disabled {
+		return nil, errors.New("account is disabled")
+	}
+
+	// failures are only cleared once the second factor has been verified
+	// too, so that knowing the password does not reset the guesses left
+	if user.TOTPEnabled {
+		return s.newTwoFactorChallenge(user, username)
+	}
+	s.throttle.Succeed(username)
+	resp, err := s.issueSession(user, "")
+	if err != nil {
+		return nil, err
+	}
+	resp.TwoFactorSetupRequired = s.TwoFactorSetupRequired(user)
+	return resp, nil
+}
+
+// CreateSession signs in a user that was authenticated elsewhere, such as
+// by single sign-on.
+func (s *AuthService) CreateSession(user *model.User) (*model.LoginResponse, error) {
+	if user.Disabled {
+		return nil, errors.New("account is disabled")
+	}
+	return s.issueSession(user, "")
+}
+
+// Refresh exchanges a refresh token for a new access token and a new
+// refresh token. Reusing a refresh token that was already exchanged revokes
+// its whole family, logging out whoever holds the current one.
+func (s *AuthService) Refresh(refreshToken string) (*model.LoginResponse, error) {
+	token, err := s.sessionRepo.FindRefreshToken(hashToken(refreshToken))
+	if err != nil {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	if token.RevokedAt != nil {
+		if err := s.sessionRepo.RevokeFamily(token.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("refresh token has been revoked")
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return nil, errors.New("refresh token expired")
+	}
+
+	revoked, err := s.sessionRepo.RevokeRefreshToken(token.ID)
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, err
+	}
+	if !revoked {
+		// lost a race with a concurrent refresh of the same token
+		return nil, errors.New("refresh token has been revoked")
+	}
+
+	user, err := s.userRepo.FindByID(uint(token.UserID))
+	if err != nil {
+		return nil, errors.New("invalid refresh token")
+	}
+	if user.Disabled {
+		return nil, errors.New("account is disabled")
 	}
 
-	if !user.CheckPassword(password) {
-		return nil, errors.New("invalid credentials")
+	return s.issueSession(user, token.FamilyID)
+}
+
+// Logout denylists the access token until it expires and revokes the
+// session's refresh tokens.
+func (s *AuthService) Logout(accessToken, refreshToken string) error {
+	claims, err := s.parseToken(accessToken)
+	if err != nil {
+		return err
+	}
+	jti, _ := claims["jti"].(string)
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil || jti == "" {
+		return errors.New("invalid token")
+	}
+	if err := s.sessionRepo.DenyAccessToken(jti, exp.Time); err != nil {
+		return err
 	}
 
-	token, err := s.generateToken(user)
+	if refreshToken == "" {
+		return nil
+	}
+	token, err := s.sessionRepo.FindRefreshToken(hashToken(refreshToken))
+	if err != nil {
+		return nil
+	}
+	if userID, _ := claims["user_id"].(float64); int64(userID) != token.UserID {
+		return errors.New("refresh token belongs to another user")
+	}
+	return s.sessionRepo.RevokeFamily(token.FamilyID)
+}
+
+// issueSession creates an access token and a refresh token. An empty
+// familyID starts a new session.
+func (s *AuthService) issueSession(user *model.User, familyID string) (*model.LoginResponse, error) {
+	accessToken, err := s.generateToken(user)
+	if err != nil {
+		return nil, err
+	}
+
+	if familyID == "" {
+		if familyID, err = randomToken(16); err != nil {
+			return nil, err
+		}
+	}
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	err = s.sessionRepo.CreateRefreshToken(&model.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: time.Now().Add(s.refreshTokenExpiry),
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	return &model.LoginResponse{
-		Token: token,
-		User:  *user,
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.tokenExpiry.Seconds()),
+		User:         user,
 	}, nil
 }
 
@@ -50,10 +232,15 @@ func (s *AuthService) Register(username, password string) (*model.User, error) {
 	if existingUser != nil {
 		return nil, errors.New("username already exists")
 	}
+	if err := s.passwordPolicy.Validate(username, password); err != nil {
+		return nil, err
+	}
 
 	user := &model.User{
-		Username: username,
-		Password: password,
+		Username:     username,
+		Password:     password,
+		Role:         model.RoleViewer,
+		AuthProvider: model.AuthProviderLocal,
 	}
 
 	if err := user.HashPassword(); err != nil {
@@ -69,39 +256,192 @@ func (s *AuthService) Register(username, password string) (*model.User, error) {
 
 func (s *AuthService) ChangePassword(username, oldPassword, newPassword string) error {
 	user, err := s.userRepo.FindByUsername(username)
-	if err != nil {
+	if err != nil || user == nil {
 		return errors.New("user not found")
 	}
 
+	if user.AuthProvider != model.AuthProviderLocal {
+		return errors.New("the password of this account is managed by " + user.AuthProvider)
+	}
+
 	if !user.CheckPassword(oldPassword) {
 		return errors.New("invalid old password")
 	}
+	if newPassword == oldPassword {
+		return errors.New("new password must differ from the old one")
+	}
+	if err := s.passwordPolicy.Validate(username, newPassword); err != nil {
+		return err
+	}
 
-	return s.userRepo.ChangePassword(username, newPassword)
+	if err := s.userRepo.ChangePassword(username, newPassword); err != nil {
+		return err
+	}
+	// sessions opened with the old password must not outlive it
+	return s.userRepo.InvalidateSessions(user.ID)
 }
 
 func (s *AuthService) ValidateToken(tokenString string) (*model.User, error) {
+	claims, err := s.parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	jti, _ := claims["jti"].(string)
+	version, hasVersion := claims["ver"].(float64)
+	userID, hasUserID := claims["user_id"].(float64)
+	if jti == "" || !hasVersion || !hasUserID {
+		return nil, errors.New("invalid token")
+	}
+
+	denied, err := s.sessionRepo.IsAccessTokenDenied(jti)
+	if err != nil {
+		return nil, err
+	}
+	if denied {
+		return nil, errors.New("token has been revoked")
+	}
+
+	user, err := s.userRepo.FindByID(uint(userID))
+	if err != nil {
+		return nil, err
+	}
+	// checked on every request so disabling a user takes effect immediately
+	if user.Disabled {
+		return nil, errors.New("account is disabled")
+	}
+	if int(version) != user.TokenVersion {
+		return nil, errors.New("token has been revoked")
+	}
+	return user, nil
+}
+
+func (s *AuthService) parseToken(tokenString string) (jwt.MapClaims, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		return s.jwtSecret, nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+// CreateAPIToken issues a personal API token for the user. The returned
+// token string is not stored and cannot be retrieved again.
+func (s *AuthService) CreateAPIToken(user *model.User, req model.CreateAPITokenRequest) (*model.CreateAPITokenResponse, error) {
+	if len(req.Scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	for _, scope := range req.Scopes {
+		if !scope.Valid() {
+			return nil, fmt.Errorf("invalid scope: %s", scope)
+		}
+		if !user.Role.Can(scope) {
+			return nil, fmt.Errorf("your role does not grant the %s scope", scope)
+		}
+	}
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		return nil, errors.New("expiry must be in the future")
+	}
 
+	secret, err := randomToken(32)
 	if err != nil {
 		return nil, err
 	}
+	raw := model.APITokenPrefix + secret
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		userID := uint(claims["user_id"].(float64))
-		return s.userRepo.FindByID(userID)
+	token := &model.APIToken{
+		UserID:    user.ID,
+		Name:      req.Name,
+		Prefix:    raw[:len(model.APITokenPrefix)+6],
+		TokenHash: hashToken(raw),
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := s.tokenRepo.Create(token); err != nil {
+		return nil, err
 	}
 
-	return nil, errors.New("invalid token")
+	return &model.CreateAPITokenResponse{Token: raw, APIToken: *token}, nil
+}
+
+func (s *AuthService) ListAPITokens(userID int64) ([]model.APIToken, error) {
+	return s.tokenRepo.ListByUser(userID)
+}
+
+func (s *AuthService) RevokeAPIToken(userID, id int64) error {
+	found, err := s.tokenRepo.Delete(userID, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("token not found")
+	}
+	return nil
+}
+
+// ValidateAPIToken resolves a personal API token to its owner.
+func (s *AuthService) ValidateAPIToken(raw string) (*model.User, *model.APIToken, error) {
+	token, err := s.tokenRepo.FindByHash(hashToken(raw))
+	if err != nil {
+		return nil, nil, errors.New("invalid token")
+	}
+
+	now := time.Now()
+	if token.ExpiresAt != nil && now.After(*token.ExpiresAt) {
+		return nil, nil, errors.New("token expired")
+	}
+
+	user, err := s.userRepo.FindByID(uint(token.UserID))
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.Disabled {
+		return nil, nil, errors.New("account is disabled")
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > apiTokenTouchInterval {
+		if err := s.tokenRepo.TouchLastUsed(token.ID, now); err != nil {
+			return nil, nil, err
+		}
+		token.LastUsedAt = &now
+	}
+	return user, token, nil
+}
+
+// hashToken hashes an API or refresh token for storage. Tokens are random,
+// so a fast hash is enough.
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
 }
 
 func (s *AuthService) generateToken(user *model.User) (string, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"user_id": user.ID,
+		"ver":     user.TokenVersion,
+		"jti":     jti,
 		"exp":     time.Now().Add(s.tokenExpiry).Unix(),
 	})
 
 	return token.SignedString(s.jwtSecret)
 }
+
+// randomToken returns n random bytes encoded as URL-safe base64.
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
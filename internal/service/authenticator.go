@@ -0,0 +1,59 @@
+package service
+
+import (
+	"errors"
+
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/repository"
+)
+
+// ErrInvalidCredentials is returned by an Authenticator that does not
+// accept the username and password, so the next one can be tried.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Authenticator verifies a username and password against one backend and
+// returns the matching user, creating or updating it if the backend owns
+// the account.
+type Authenticator interface {
+	Name() string
+	Authenticate(username, password string) (*model.User, error)
+}
+
+// LocalAuthenticator checks passwords stored in the database.
+type LocalAuthenticator struct {
+	userRepo *repository.UserRepository
+}
+
+func NewLocalAuthenticator(userRepo *repository.UserRepository) *LocalAuthenticator {
+	return &LocalAuthenticator{userRepo: userRepo}
+}
+
+func (a *LocalAuthenticator) Name() string {
+	return model.AuthProviderLocal
+}
+
+func (a *LocalAuthenticator) Authenticate(username, password string) (*model.User, error) {
+	user, err := a.userRepo.FindByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.AuthProvider != model.AuthProviderLocal || !user.CheckPassword(password) {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// mapGroupsToRole returns the most privileged role mapped from the groups,
+// or defaultRole if none match, and whether a mapping is configured at all.
+func mapGroupsToRole(groups []string, mapping map[string]model.Role, defaultRole model.Role) (model.Role, bool) {
+	if len(mapping) == 0 {
+		return defaultRole, false
+	}
+	role := defaultRole
+	for _, group := range groups {
+		if mapped, ok := mapping[group]; ok && mapped.Rank() > role.Rank() {
+			role = mapped
+		}
+	}
+	return role, true
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gogo-scheduler/internal/model"
+)
+
+// chatFormats build the messages of the chat webhook formats from a
+// notification.
+var chatFormats = map[string]func(n *model.Notification) map[string]any{
+	model.WebhookFormatSlack:    slackMessage,
+	model.WebhookFormatTeams:    teamsMessage,
+	model.WebhookFormatDingTalk: dingTalkMessage,
+	model.WebhookFormatFeishu:   feishuMessage,
+}
+
+// chatStatus describes how an event is shown in chat messages.
+type chatStatus struct {
+	verb  string // completes "<script> ..."
+	color string // hex color for Slack and DingTalk
+	teams string // Adaptive Card text color
+	lark  string // Feishu card header template
+}
+
+var chatStatuses = map[string]chatStatus{
+	model.EventTaskStarted:   {"started", "#1D9BD1", "accent", "blue"},
+	model.EventTaskSucceeded: {"succeeded", "#2EB67D", "good", "green"},
+	model.EventTaskFailed:    {"failed", "#E01E5A", "attention", "red"},
+	model.EventTaskTimedOut:  {"timed out", "#ECB22E", "warning", "orange"},
+}
+
+func statusOf(n *model.Notification) chatStatus {
+	if status, ok := chatStatuses[n.Event]; ok {
+		return status
+	}
+	return chatStatus{"", "#9E9E9E", "default", "grey"}
+}
+
+func chatTitle(n *model.Notification) string {
+	if n.Event == EventTest {
+		return "Test notification from gogo-scheduler"
+	}
+	return fmt.Sprintf("%s %s", n.Script.Name, statusOf(n).verb)
+}
+
+// chatFacts returns the task details shown in chat messages as name and
+// value pairs.
+func chatFacts(n *model.Notification) [][2]string {
+	facts := [][2]string{
+		{"Task", fmt.Sprintf("#%d %s", n.Task.ID, n.Task.Name)},
+		{"Status", n.Task.Status},
+	}
+	if n.Task.DurationMs > 0 {
+		facts = append(facts, [2]string{"Duration", (time.Duration(n.Task.DurationMs) * time.Millisecond).String()})
+	}
+	if n.Task.Error != "" {
+		facts = append(facts, [2]string{"Error", n.Task.Error})
+	}
+	return facts
+}
+
+// slackMessage builds a message with a colored attachment, which Slack
+// and compatible chats such as Mattermost and Rocket.Chat render.
+func slackMessage(n *model.Notification) map[string]any {
+	fields := []map[string]any{}
+	for _, fact := range chatFacts(n) {
+		fields = append(fields, map[string]any{"title": fact[0], "value": fact[1], "short": fact[0] != "Error"})
+	}
+	attachment := map[string]any{
+		"color":    statusOf(n).color,
+		"fallback": chatTitle(n),
+		"title":    chatTitle(n),
+		"fields":   fields,
+		"footer":   "gogo-scheduler",
+		"ts":       n.Timestamp.Unix(),
+	}
+	if n.Task.URL != "" {
+		attachment["title_link"] = n.Task.URL
+	}
+	return map[string]any{
+		"text":        chatTitle(n),
+		"attachments": []any{attachment},
+	}
+}
+
+// teamsMessage builds an Adaptive Card for Microsoft Teams workflow
+// webhooks.
+func teamsMessage(n *model.Notification) map[string]any {
+	facts := []map[string]any{}
+	for _, fact := range chatFacts(n) {
+		facts = append(facts, map[string]any{"title": fact[0], "value": fact[1]})
+	}
+	card := map[string]any{
+		"type":    "AdaptiveCard",
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"version": "1.4",
+		"body": []any{
+			map[string]any{"type": "TextBlock", "text": chatTitle(n), "weight": "bolder", "size": "medium", "color": statusOf(n).teams, "wrap": true},
+			map[string]any{"type": "FactSet", "facts": facts},
+		},
+	}
+	if n.Task.URL != "" {
+		card["actions"] = []any{map[string]any{"type": "Action.OpenUrl", "title": "View task", "url": n.Task.URL}}
+	}
+	return map[string]any{
+		"type": "message",
+		"attachments": []any{map[string]any{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content":     card,
+		}},
+	}
+}
+
+// dingTalkMessage builds a markdown message for DingTalk robots.
+func dingTalkMessage(n *model.Notification) map[string]any {
+	var text strings.Builder
+	fmt.Fprintf(&text, "### <font color=\"%s\">%s</font>\n\n", statusOf(n).color, chatTitle(n))
+	for _, fact := range chatFacts(n) {
+		fmt.Fprintf(&text, "- **%s**: %s\n", fact[0], fact[1])
+	}
+	if n.Task.URL != "" {
+		fmt.Fprintf(&text, "\n[View task](%s)\n", n.Task.URL)
+	}
+	return map[string]any{
+		"msgtype":  "markdown",
+		"markdown": map[string]any{"title": chatTitle(n), "text": text.String()},
+	}
+}
+
+// feishuMessage builds an interactive card for Feishu and Lark bots.
+func feishuMessage(n *model.Notification) map[string]any {
+	fields := []any{}
+	for _, fact := range chatFacts(n) {
+		fields = append(fields, map[string]any{
+			"is_short": fact[0] != "Error",
+			"text":     map[string]any{"tag": "lark_md", "content": fmt.Sprintf("**%s**\n%s", fact[0], fact[1])},
+		})
+	}
+	elements := []any{map[string]any{"tag": "div", "fields": fields}}
+	if n.Task.URL != "" {
+		elements = append(elements, map[string]any{
+			"tag": "action",
+			"actions": []any{map[string]any{
+				"tag":  "button",
+				"text": map[string]any{"tag": "plain_text", "content": "View task"},
+				"type": "primary",
+				"url":  n.Task.URL,
+			}},
+		})
+	}
+	return map[string]any{
+		"msg_type": "interactive",
+		"card": map[string]any{
+			"header": map[string]any{
+				"title":    map[string]any{"tag": "plain_text", "content": chatTitle(n)},
+				"template": statusOf(n).lark,
+			},
+			"elements": elements,
+		},
+	}
+}
+
+// signDingTalk adds the timestamp and signature DingTalk robots with a
+// secret require to the webhook URL.
+func signDingTalk(webhookURL, secret string, now time.Time) (string, error) {
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return "", err
+	}
+	timestamp := strconv.FormatInt(now.UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + secret))
+
+	query := u.Query()
+	query.Set("timestamp", timestamp)
+	query.Set("sign", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// signFeishu adds the timestamp and signature Feishu bots with a secret
+// require to the message.
+func signFeishu(message map[string]any, secret string, now time.Time) {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(timestamp+"\n"+secret))
+	message["timestamp"] = timestamp
+	message["sign"] = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// chatError returns the error DingTalk and Feishu report in the body of
+// successful responses, if any.
+func chatError(format string, body []byte) error {
+	var result struct {
+		ErrCode *int   `json:"errcode"` // DingTalk
+		ErrMsg  string `json:"errmsg"`
+		Code    *int   `json:"code"` // Feishu
+		Msg     string `json:"msg"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil
+	}
+	switch {
+	case format == model.WebhookFormatDingTalk && result.ErrCode != nil && *result.ErrCode != 0:
+		return fmt.Errorf("dingtalk error %d: %s", *result.ErrCode, result.ErrMsg)
+	case format == model.WebhookFormatFeishu && result.Code != nil && *result.Code != 0:
+		return fmt.Errorf("feishu error %d: %s", *result.Code, result.Msg)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"testing"
+
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/repository"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB returns a migrated in-memory database that is dropped when the
+// test ends.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection to :memory: opens a new database
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	err = db.AutoMigrate(&model.Script{}, &model.Task{}, &model.User{}, &model.RetentionPolicy{}, &model.Artifact{}, &model.APIToken{},
+		&model.RefreshToken{}, &model.RevokedAccessToken{}, &model.RecoveryCode{}, &model.SecuritySettings{},
+		&model.AuditEvent{}, &model.ScriptShare{}, &model.Group{}, &model.GroupMember{},
+		&model.Project{}, &model.ProjectMember{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// newTestAuthService returns an AuthService backed by db with the given
+// authenticators.
+func newTestAuthService(db *gorm.DB, authenticators ...Authenticator) *AuthService {
+	return NewAuthService(repository.NewUserRepository(db), repository.NewAPITokenRepository(db), repository.NewSessionRepository(db),
+		repository.NewTwoFactorRepository(db), AuthConfig{JWTSecret: "test-secret"}, authenticators...)
+}
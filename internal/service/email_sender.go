@@ -0,0 +1,219 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"gogo-scheduler/internal/model"
+)
+
+const (
+	defaultEmailSubject = `[{{.Script.Name}}] {{.Event}}: task #{{.Task.ID}} {{.Task.Status}}`
+	defaultEmailBody    = `Script: {{.Script.Name}}
+Task: #{{.Task.ID}} {{.Task.Name}}
+Status: {{.Task.Status}}
+{{- with .Task.StartTime}}
+Started: {{.Format "2006-01-02 15:04:05 MST"}}{{end}}
+{{- with .Task.EndTime}}
+Finished: {{.Format "2006-01-02 15:04:05 MST"}}{{end}}
+{{- if .Task.DurationMs}}
+Duration: {{duration .Task.DurationMs}}{{end}}
+{{- with .Task.Error}}
+Error: {{.}}{{end}}
+{{- with .Task.Output}}
+
+Output (last 20 lines):
+{{tail 20 .}}{{end}}
+{{- with .Task.ErrorOutput}}
+
+Error output (last 20 lines):
+{{tail 20 .}}{{end}}
+`
+)
+
+// emailFuncs are available in the subject and body templates.
+var emailFuncs = template.FuncMap{
+	// tail returns the last n lines of s.
+	"tail": func(n int, s string) string {
+		lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+		if len(lines) > n {
+			lines = lines[len(lines)-n:]
+		}
+		return strings.Join(lines, "\n")
+	},
+	// duration formats milliseconds, such as DurationMs, as a duration.
+	"duration": func(ms int64) string {
+		return (time.Duration(ms) * time.Millisecond).String()
+	},
+}
+
+// EmailSender sends notifications as plain text emails through the
+// channel's SMTP server. The channel secret is the password used with the
+// username; servers other than localhost must support STARTTLS for it to
+// be sent.
+type EmailSender struct {
+	dialer *net.Dialer
+}
+
+func NewEmailSender() *EmailSender {
+	return &EmailSender{dialer: &net.Dialer{Timeout: 10 * time.Second}}
+}
+
+// Send returns the SMTP reply code of the server if it rejected the email.
+func (e *EmailSender) Send(ctx context.Context, channel *model.NotificationChannel, recipients []string, n *model.Notification) (int, error) {
+	cfg := channel.Email
+	if len(recipients) == 0 {
+		return 0, errors.New("no recipients")
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return 0, fmt.Errorf("invalid sender: %w", err)
+	}
+	to, err := parseAddresses(recipients)
+	if err != nil {
+		return 0, err
+	}
+	msg, err := composeEmail(cfg, from, to, n)
+	if err != nil {
+		return 0, err
+	}
+
+	conn, err := e.dialer.DialContext(ctx, "tcp", net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)))
+	if err != nil {
+		return 0, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return smtpCode(err), err
+	}
+	defer client.Close()
+
+	if cfg.StartTLS {
+		if err := client.StartTLS(&tls.Config{ServerName: cfg.Host}); err != nil {
+			return smtpCode(err), err
+		}
+	}
+	if cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, channel.Secret, cfg.Host)); err != nil {
+			return smtpCode(err), err
+		}
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return smtpCode(err), err
+	}
+	for _, addr := range to {
+		if err := client.Rcpt(addr.Address); err != nil {
+			return smtpCode(err), err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return smtpCode(err), err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return smtpCode(err), err
+	}
+	if err := w.Close(); err != nil {
+		return smtpCode(err), err
+	}
+	client.Quit()
+	return 250, nil
+}
+
+// composeEmail renders the templates of the channel into a message.
+func composeEmail(cfg *model.EmailConfig, from *mail.Address, to []*mail.Address, n *model.Notification) ([]byte, error) {
+	subjectTmpl, bodyTmpl, err := parseEmailTemplates(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var subject, body bytes.Buffer
+	if err := subjectTmpl.Execute(&subject, n); err != nil {
+		return nil, fmt.Errorf("subject template: %w", err)
+	}
+	if err := bodyTmpl.Execute(&body, n); err != nil {
+		return nil, fmt.Errorf("body template: %w", err)
+	}
+
+	recipients := make([]string, len(to))
+	for i, addr := range to {
+		recipients[i] = addr.String()
+	}
+
+	var msg bytes.Buffer
+	header := func(name, value string) {
+		fmt.Fprintf(&msg, "%s: %s\r\n", name, value)
+	}
+	header("From", from.String())
+	header("To", strings.Join(recipients, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", strings.Join(strings.Fields(subject.String()), " ")))
+	header("Date", n.Timestamp.Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	header("X-Gogo-Event", n.Event)
+	msg.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&msg)
+	qp.Write(bytes.ReplaceAll(body.Bytes(), []byte("\n"), []byte("\r\n")))
+	qp.Close()
+	return msg.Bytes(), nil
+}
+
+// parseEmailTemplates parses the subject and body templates of a channel,
+// falling back to the defaults.
+func parseEmailTemplates(cfg *model.EmailConfig) (*template.Template, *template.Template, error) {
+	subject, body := cfg.Subject, cfg.Body
+	if subject == "" {
+		subject = defaultEmailSubject
+	}
+	if body == "" {
+		body = defaultEmailBody
+	}
+	subjectTmpl, err := template.New("subject").Funcs(emailFuncs).Parse(subject)
+	if err != nil {
+		return nil, nil, fmt.Errorf("subject template: %w", err)
+	}
+	bodyTmpl, err := template.New("body").Funcs(emailFuncs).Parse(body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("body template: %w", err)
+	}
+	return subjectTmpl, bodyTmpl, nil
+}
+
+func parseAddresses(addrs []string) ([]*mail.Address, error) {
+	parsed := make([]*mail.Address, len(addrs))
+	for i, addr := range addrs {
+		a, err := mail.ParseAddress(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %q: %w", addr, err)
+		}
+		parsed[i] = a
+	}
+	return parsed, nil
+}
+
+// smtpCode returns the reply code of an SMTP error, or 0.
+func smtpCode(err error) int {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return protoErr.Code
+	}
+	return 0
+}
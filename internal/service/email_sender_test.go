@@ -0,0 +1,272 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gogo-scheduler/internal/model"
+)
+
+// smtpSink is a local SMTP server that records the messages it accepts.
+// Recipients listed in reject get the given reply code.
+type smtpSink struct {
+	listener net.Listener
+	username string
+	password string
+	reject   map[string]int
+
+	mu       sync.Mutex
+	messages []sinkMessage
+}
+
+type sinkMessage struct {
+	from string
+	to   []string
+	auth string // "user:password" if the client authenticated
+	data *mail.Message
+	body string
+}
+
+func newSMTPSink(t *testing.T, username, password string) *smtpSink {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpSink{listener: listener, username: username, password: password, reject: map[string]int{}}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpSink) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpSink) received() []sinkMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.messages)
+}
+
+func (s *smtpSink) serve(c net.Conn) {
+	defer c.Close()
+	conn := textproto.NewConn(c)
+	conn.PrintfLine("220 sink ESMTP")
+
+	var msg sinkMessage
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			conn.PrintfLine("250-sink")
+			conn.PrintfLine("250 AUTH PLAIN")
+		case "AUTH":
+			creds, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(arg, "PLAIN "))
+			parts := strings.Split(string(creds), "\x00")
+			if len(parts) != 3 || parts[1] != s.username || parts[2] != s.password {
+				conn.PrintfLine("535 authentication failed")
+				continue
+			}
+			msg.auth = parts[1] + ":" + parts[2]
+			conn.PrintfLine("235 authenticated")
+		case "MAIL":
+			msg.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			conn.PrintfLine("250 ok")
+		case "RCPT":
+			to := strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>")
+			if code := s.reject[to]; code != 0 {
+				conn.PrintfLine("%d recipient rejected", code)
+				continue
+			}
+			msg.to = append(msg.to, to)
+			conn.PrintfLine("250 ok")
+		case "DATA":
+			conn.PrintfLine("354 go ahead")
+			data, err := mail.ReadMessage(conn.DotReader())
+			if err != nil {
+				conn.PrintfLine("554 %v", err)
+				continue
+			}
+			body, _ := io.ReadAll(data.Body)
+			msg.data, msg.body = data, string(body)
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			msg = sinkMessage{auth: msg.auth}
+			conn.PrintfLine("250 queued")
+		case "QUIT":
+			conn.PrintfLine("221 bye")
+			return
+		default:
+			conn.PrintfLine("250 ok")
+		}
+	}
+}
+
+func newTestEmailChannel(sink *smtpSink) *model.NotificationChannel {
+	return &model.NotificationChannel{
+		Type:   model.ChannelEmail,
+		Secret: "smtp-pass",
+		Email: &model.EmailConfig{
+			Host:     "127.0.0.1",
+			Port:     sink.port(),
+			Username: "scheduler",
+			From:     "Scheduler <scheduler@example.com>",
+		},
+	}
+}
+
+func newTestNotification() *model.Notification {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	end := start.Add(90 * time.Second)
+	var output strings.Builder
+	for i := 1; i <= 30; i++ {
+		fmt.Fprintf(&output, "line %d\n", i)
+	}
+	return &model.Notification{
+		Event:     model.EventTaskFailed,
+		Timestamp: end,
+		Task: model.NotificationTask{
+			ID: 42, Name: "backup", Status: "failed", Error: "exit status 1",
+			StartTime: &start, EndTime: &end, DurationMs: 90000, Output: output.String(),
+		},
+		Script: model.NotificationScript{Name: "nightly backup"},
+	}
+}
+
+func TestEmailSenderSend(t *testing.T) {
+	sink := newSMTPSink(t, "scheduler", "smtp-pass")
+	channel := newTestEmailChannel(sink)
+
+	status, err := NewEmailSender().Send(context.Background(), channel, []string{"oncall@example.com", "Ops <ops@example.com>"}, newTestNotification())
+	if err != nil || status != 250 {
+		t.Fatalf("Send = %d, %v; want 250", status, err)
+	}
+
+	messages := sink.received()
+	if len(messages) != 1 {
+		t.Fatalf("sink received %d messages, want 1", len(messages))
+	}
+	msg := messages[0]
+	if msg.auth != "scheduler:smtp-pass" {
+		t.Errorf("authenticated as %q", msg.auth)
+	}
+	if msg.from != "scheduler@example.com" || strings.Join(msg.to, ",") != "oncall@example.com,ops@example.com" {
+		t.Errorf("envelope from %q to %v", msg.from, msg.to)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.data.Header.Get("Subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[nightly backup] task.failed: task #42 failed"; subject != want {
+		t.Errorf("subject = %q, want %q", subject, want)
+	}
+	if got := msg.data.Header.Get("X-Gogo-Event"); got != model.EventTaskFailed {
+		t.Errorf("X-Gogo-Event = %q", got)
+	}
+	for _, want := range []string{"Status: failed", "Duration: 1m30s", "Error: exit status 1", "line 11\n", "line 30"} {
+		if !strings.Contains(msg.body, want) {
+			t.Errorf("body does not contain %q:\n%s", want, msg.body)
+		}
+	}
+	if strings.Contains(msg.body, "line 10\n") {
+		t.Errorf("body has more than the last 20 lines of output:\n%s", msg.body)
+	}
+}
+
+func TestEmailSenderTemplates(t *testing.T) {
+	sink := newSMTPSink(t, "scheduler", "smtp-pass")
+	channel := newTestEmailChannel(sink)
+	channel.Email.Subject = "{{.Task.Status}}: {{.Script.Name}}"
+	channel.Email.Body = "{{tail 2 .Task.Output}}"
+
+	if _, err := NewEmailSender().Send(context.Background(), channel, []string{"oncall@example.com"}, newTestNotification()); err != nil {
+		t.Fatal(err)
+	}
+	msg := sink.received()[0]
+	if subject := msg.data.Header.Get("Subject"); subject != "failed: nightly backup" {
+		t.Errorf("subject = %q", subject)
+	}
+	if msg.body != "line 29\nline 30\n" {
+		t.Errorf("body = %q", msg.body)
+	}
+}
+
+func TestEmailSenderRejections(t *testing.T) {
+	sink := newSMTPSink(t, "scheduler", "smtp-pass")
+	sink.reject["full@example.com"] = 452
+	sink.reject["gone@example.com"] = 550
+	sender := NewEmailSender()
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		recipient string
+		status    int
+		retryable bool
+	}{
+		{"full@example.com", 452, true},
+		{"gone@example.com", 550, false},
+	} {
+		status, err := sender.Send(ctx, newTestEmailChannel(sink), []string{tc.recipient}, newTestNotification())
+		if err == nil || status != tc.status {
+			t.Errorf("Send to %s = %d, %v; want %d", tc.recipient, status, err, tc.status)
+			continue
+		}
+		if got := retryable(model.ChannelEmail, status, err); got != tc.retryable {
+			t.Errorf("retryable(%d) = %v, want %v", status, got, tc.retryable)
+		}
+	}
+
+	channel := newTestEmailChannel(sink)
+	channel.Secret = "wrong"
+	if status, err := sender.Send(ctx, channel, []string{"oncall@example.com"}, newTestNotification()); err == nil || status != 535 {
+		t.Errorf("Send with a wrong password = %d, %v; want 535", status, err)
+	}
+
+	if status, err := sender.Send(ctx, newTestEmailChannel(sink), nil, newTestNotification()); err == nil || status != 0 {
+		t.Errorf("Send without recipients = %d, %v; want an error", status, err)
+	}
+	if len(sink.received()) != 0 {
+		t.Errorf("sink received %d messages, want none", len(sink.received()))
+	}
+}
+
+func TestEmailSenderUnreachableServer(t *testing.T) {
+	sink := newSMTPSink(t, "scheduler", "smtp-pass")
+	channel := newTestEmailChannel(sink)
+	sink.listener.Close()
+
+	status, err := NewEmailSender().Send(context.Background(), channel, []string{"oncall@example.com"}, newTestNotification())
+	if err == nil {
+		t.Fatal("Send to a closed port succeeded")
+	}
+	if !retryable(model.ChannelEmail, status, err) {
+		t.Errorf("connection failure %v is not retried", err)
+	}
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"errors"
+
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/repository"
+)
+
+type GroupService struct {
+	groupRepo *repository.GroupRepository
+	userRepo  *repository.UserRepository
+}
+
+func NewGroupService(groupRepo *repository.GroupRepository, userRepo *repository.UserRepository) *GroupService {
+	return &GroupService{groupRepo: groupRepo, userRepo: userRepo}
+}
+
+func (s *GroupService) ListGroups() ([]model.Group, error) {
+	return s.groupRepo.List()
+}
+
+func (s *GroupService) GetGroup(id int64) (*model.Group, error) {
+	return s.groupRepo.GetByID(id)
+}
+
+func (s *GroupService) CreateGroup(req model.GroupRequest) (*model.Group, error) {
+	group := &model.Group{Name: req.Name, Description: req.Description}
+	if err := s.groupRepo.Create(group); err != nil {
+		return nil, errors.New("group name already exists")
+	}
+	return group, nil
+}
+
+// DeleteGroup deletes a group. Scripts shared with it are no longer shared
+// with its members.
+func (s *GroupService) DeleteGroup(id int64) error {
+	if _, err := s.groupRepo.GetByID(id); err != nil {
+		return errors.New("group not found")
+	}
+	return s.groupRepo.Delete(id)
+}
+
+func (s *GroupService) AddMember(groupID, userID int64) (*model.Group, error) {
+	if _, err := s.groupRepo.GetByID(groupID); err != nil {
+		return nil, errors.New("group not found")
+	}
+	if _, err := s.userRepo.FindByID(uint(userID)); err != nil {
+		return nil, errors.New("user not found")
+	}
+	if err := s.groupRepo.AddMember(groupID, userID); err != nil {
+		return nil, err
+	}
+	return s.groupRepo.GetByID(groupID)
+}
+
+func (s *GroupService) RemoveMember(groupID, userID int64) (*model.Group, error) {
+	if _, err := s.groupRepo.GetByID(groupID); err != nil {
+		return nil, errors.New("group not found")
+	}
+	if err := s.groupRepo.RemoveMember(groupID, userID); err != nil {
+		return nil, err
+	}
+	return s.groupRepo.GetByID(groupID)
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/repository"
+
+	"github.com/panjf2000/ants/v2"
+)
+
+// healthCheckTimeout bounds the database checks of a readiness check.
+const healthCheckTimeout = 5 * time.Second
+
+// Loop is a background job expected to run every Interval. It is
+// considered stuck when it has not run for two intervals.
+type Loop struct {
+	Name     string
+	Interval time.Duration
+	LastRun  func() time.Time
+}
+
+// HealthService checks whether the scheduler is ready to serve requests
+// and run scripts.
+type HealthService struct {
+	repo   *repository.HealthRepository
+	models []any
+	pool   *ants.Pool
+	loops  []Loop
+}
+
+// NewHealthService returns a service checking the database, that the tables
+// of models are migrated, the worker pool and loops.
+func NewHealthService(repo *repository.HealthRepository, models []any, pool *ants.Pool, loops ...Loop) *HealthService {
+	return &HealthService{repo: repo, models: models, pool: pool, loops: loops}
+}
+
+// Check checks all components. The report is failing if any of them is.
+func (s *HealthService) Check(ctx context.Context) *model.HealthReport {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	report := &model.HealthReport{
+		Status:     model.HealthOK,
+		CheckedAt:  time.Now(),
+		Components: make(map[string]model.ComponentHealth),
+	}
+	report.Components["database"] = s.checkDatabase(ctx)
+	report.Components["migrations"] = s.checkMigrations(ctx)
+	report.Components["worker_pool"] = s.checkWorkerPool()
+	for _, loop := range s.loops {
+		report.Components[loop.Name] = checkLoop(loop, report.CheckedAt)
+	}
+	for _, component := range report.Components {
+		if component.Status != model.HealthOK {
+			report.Status = model.HealthFailing
+		}
+	}
+	return report
+}
+
+func (s *HealthService) checkDatabase(ctx context.Context) model.ComponentHealth {
+	start := time.Now()
+	if err := s.repo.Ping(ctx); err != nil {
+		return failing(err, nil)
+	}
+	return model.ComponentHealth{
+		Status:  model.HealthOK,
+		Details: map[string]any{"latency_ms": time.Since(start).Milliseconds()},
+	}
+}
+
+func (s *HealthService) checkMigrations(ctx context.Context) model.ComponentHealth {
+	missing, err := s.repo.MissingSchema(ctx, s.models...)
+	if err != nil {
+		return failing(err, nil)
+	}
+	if len(missing) > 0 {
+		return failing(fmt.Errorf("missing tables or columns: %s", strings.Join(missing, ", ")), map[string]any{"missing": missing})
+	}
+	return model.ComponentHealth{Status: model.HealthOK, Details: map[string]any{"tables": len(s.models)}}
+}
+
+// checkWorkerPool checks that the pool runs tasks. Runs wait for a worker
+// when all are busy, so a full pool still accepts work.
+func (s *HealthService) checkWorkerPool() model.ComponentHealth {
+	details := map[string]any{
+		"capacity": s.pool.Cap(),
+		"running":  s.pool.Running(),
+		"free":     s.pool.Free(),
+		"waiting":  s.pool.Waiting(),
+	}
+	if s.pool.IsClosed() {
+		return failing(ants.ErrPoolClosed, details)
+	}
+	return model.ComponentHealth{Status: model.HealthOK, Details: details}
+}
+
+func checkLoop(loop Loop, now time.Time) model.ComponentHealth {
+	last := loop.LastRun()
+	if last.IsZero() {
+		return failing(errors.New("not running"), nil)
+	}
+	details := map[string]any{
+		"last_run":         last,
+		"interval_seconds": loop.Interval.Seconds(),
+	}
+	if now.Sub(last) > 2*loop.Interval {
+		return failing(fmt.Errorf("has not run since %s", last.Format(time.RFC3339)), details)
+	}
+	return model.ComponentHealth{Status: model.HealthOK, Details: details}
+}
+
+func failing(err error, details map[string]any) model.ComponentHealth {
+	return model.ComponentHealth{Status: model.HealthFailing, Error: err.Error(), Details: details}
+}
+
+// heartbeat records when a background loop last ran.
+type heartbeat struct {
+	last atomic.Int64
+}
+
+func (h *heartbeat) beat() {
+	h.last.Store(time.Now().UnixNano())
+}
+
+func (h *heartbeat) Last() time.Time {
+	n := h.last.Load()
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"time"
+
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/repository"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+type LDAPConfig struct {
+	URL                string // ldap://host:389 or ldaps://host:636
+	StartTLS           bool   // upgrade ldap:// connections with StartTLS
+	InsecureSkipVerify bool
+	CAFile             string // PEM file with the CA certificates to trust
+	Timeout            time.Duration
+
+	// BindDN and BindPassword are the service account used to search for
+	// users and groups. Leave empty to search anonymously.
+	BindDN       string
+	BindPassword string
+
+	BaseDN            string
+	UserFilter        string // e.g. (uid=%s); %s is replaced by the escaped username
+	UsernameAttribute string // attribute holding the canonical username, e.g. uid
+
+	// Groups are read from the user's memberOf attribute and, if GroupBaseDN
+	// is set, searched with GroupFilter (%s is replaced by the user DN).
+	// RoleMapping keys may be group DNs or GroupAttribute values.
+	GroupBaseDN    string
+	GroupFilter    string
+	GroupAttribute string
+	RoleMapping    map[string]model.Role
+	DefaultRole    model.Role
+}
+
+// LDAPAuthenticator authenticates users against an LDAP or Active Directory
+// server by searching for the user entry and binding as it. Users are
+// created on their first login and their role is synced from their groups.
+type LDAPAuthenticator struct {
+	cfg       LDAPConfig
+	tlsConfig *tls.Config
+	userRepo  *repository.UserRepository
+}
+
+func NewLDAPAuthenticator(cfg LDAPConfig, userRepo *repository.UserRepository) (*LDAPAuthenticator, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LDAP URL: %w", err)
+	}
+	host, _, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		host = u.Host
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.CAFile)
+		}
+	}
+
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = "(uid=%s)"
+	}
+	if cfg.UsernameAttribute == "" {
+		cfg.UsernameAttribute = "uid"
+	}
+	if cfg.GroupFilter == "" {
+		cfg.GroupFilter = "(member=%s)"
+	}
+	if cfg.GroupAttribute == "" {
+		cfg.GroupAttribute = "cn"
+	}
+	if cfg.DefaultRole == "" {
+		cfg.DefaultRole = model.RoleViewer
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	return &LDAPAuthenticator{cfg: cfg, tlsConfig: tlsConfig, userRepo: userRepo}, nil
+}
+
+func (a *LDAPAuthenticator) Name() string {
+	return model.AuthProviderLDAP
+}
+
+func (a *LDAPAuthenticator) Authenticate(username, password string) (*model.User, error) {
+	// An empty password would be an unauthenticated bind, which many
+	// servers accept
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := a.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := a.bindServiceAccount(conn); err != nil {
+		return nil, err
+	}
+
+	entry, err := a.findUser(conn, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	// Search groups as the service account, the user may not be allowed to
+	if err := a.bindServiceAccount(conn); err != nil {
+		return nil, err
+	}
+	groups, err := a.groups(conn, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	if canonical := entry.GetAttributeValue(a.cfg.UsernameAttribute); canonical != "" {
+		username = canonical
+	}
+	return a.syncUser(username, entry.DN, groups)
+}
+
+func (a *LDAPAuthenticator) connect() (*ldap.Conn, error) {
+	dialer := &net.Dialer{Timeout: a.cfg.Timeout}
+	conn, err := ldap.DialURL(a.cfg.URL, ldap.DialWithTLSDialer(a.tlsConfig, dialer))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(a.cfg.Timeout)
+
+	if a.cfg.StartTLS {
+		if err := conn.StartTLS(a.tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (a *LDAPAuthenticator) bindServiceAccount(conn *ldap.Conn) error {
+	if a.cfg.BindDN == "" {
+		return conn.UnauthenticatedBind("")
+	}
+	return conn.Bind(a.cfg.BindDN, a.cfg.BindPassword)
+}
+
+func (a *LDAPAuthenticator) findUser(conn *ldap.Conn, username string) (*ldap.Entry, error) {
+	result, err := conn.Search(ldap.NewSearchRequest(
+		a.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(a.cfg.Timeout.Seconds()), false,
+		fmt.Sprintf(a.cfg.UserFilter, ldap.EscapeFilter(username)),
+		[]string{"dn", a.cfg.UsernameAttribute, "memberOf"},
+		nil,
+	))
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) || ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	if len(result.Entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	return result.Entries[0], nil
+}
+
+// groups returns the DNs and names of the groups the user belongs to.
+func (a *LDAPAuthenticator) groups(conn *ldap.Conn, entry *ldap.Entry) ([]string, error) {
+	var groups []string
+	for _, dn := range entry.GetAttributeValues("memberOf") {
+		groups = append(groups, dn)
+		if parsed, err := ldap.ParseDN(dn); err == nil && len(parsed.RDNs) > 0 && len(parsed.RDNs[0].Attributes) > 0 {
+			groups = append(groups, parsed.RDNs[0].Attributes[0].Value)
+		}
+	}
+
+	if a.cfg.GroupBaseDN == "" {
+		return groups, nil
+	}
+	result, err := conn.Search(ldap.NewSearchRequest(
+		a.cfg.GroupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		0, int(a.cfg.Timeout.Seconds()), false,
+		fmt.Sprintf(a.cfg.GroupFilter, ldap.EscapeFilter(entry.DN)),
+		[]string{"dn", a.cfg.GroupAttribute},
+		nil,
+	))
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range result.Entries {
+		groups = append(groups, group.DN)
+		if name := group.GetAttributeValue(a.cfg.GroupAttribute); name != "" {
+			groups = append(groups, name)
+		}
+	}
+	return groups, nil
+}
+
+// syncUser creates the user on first login and keeps the role in line with
+// the LDAP groups. Accounts with the same name from another provider are
+// left alone.
+func (a *LDAPAuthenticator) syncUser(username, dn string, groups []string) (*model.User, error) {
+	role, mapped := mapGroupsToRole(groups, a.cfg.RoleMapping, a.cfg.DefaultRole)
+
+	user, err := a.userRepo.FindByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		if user.AuthProvider != model.AuthProviderLDAP {
+			return nil, ErrInvalidCredentials
+		}
+		if mapped && user.Role != role {
+			if err := a.userRepo.UpdateRole(user.ID, role); err != nil {
+				return nil, err
+			}
+			user.Role = role
+		}
+		return user, nil
+	}
+
+	// LDAP users never sign in with a local password; store an unusable one
+	password, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	user = &model.User{
+		Username:     username,
+		Password:     password,
+		Role:         role,
+		AuthProvider: model.AuthProviderLDAP,
+		ExternalID:   dn,
+	}
+	if err := user.HashPassword(); err != nil {
+		return nil, err
+	}
+	if err := a.userRepo.Create(user); err != nil {
+		return nil, errors.Join(errors.New("creating LDAP user"), err)
+	}
+	return user, nil
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/repository"
+)
+
+const (
+	ldapBaseDN  = "dc=example,dc=org"
+	ldapAliceDN = "uid=alice,ou=people,dc=example,dc=org"
+	ldapBobDN   = "uid=bob,ou=people,dc=example,dc=org"
+)
+
+// testDirectory has a service account, alice in the ops group through
+// memberOf and bob in the admins group through the group's member list.
+var testDirectory = []ldapEntry{
+	{dn: "cn=svc,dc=example,dc=org", password: "svc-pass", attrs: map[string][]string{"cn": {"svc"}}},
+	{dn: ldapAliceDN, password: "alice-pass", attrs: map[string][]string{
+		"uid":      {"alice"},
+		"memberOf": {"cn=ops,ou=groups,dc=example,dc=org"},
+	}},
+	{dn: ldapBobDN, password: "bob-pass", attrs: map[string][]string{"uid": {"bob"}}},
+	{dn: "cn=admins,ou=groups,dc=example,dc=org", attrs: map[string][]string{
+		"cn":     {"admins"},
+		"member": {ldapBobDN},
+	}},
+}
+
+func newTestLDAPAuthenticator(t *testing.T, cfg LDAPConfig) (*LDAPAuthenticator, *repository.UserRepository) {
+	t.Helper()
+	userRepo := repository.NewUserRepository(newTestDB(t))
+	if cfg.BaseDN == "" {
+		cfg.BaseDN = ldapBaseDN
+	}
+	if cfg.BindDN == "" {
+		cfg.BindDN, cfg.BindPassword = "cn=svc,dc=example,dc=org", "svc-pass"
+	}
+	auth, err := NewLDAPAuthenticator(cfg, userRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return auth, userRepo
+}
+
+func TestLDAPAuthenticator(t *testing.T) {
+	server := newLDAPServer(t, nil, testDirectory...)
+	auth, userRepo := newTestLDAPAuthenticator(t, LDAPConfig{
+		URL:         server.url(),
+		GroupBaseDN: "ou=groups," + ldapBaseDN,
+		RoleMapping: map[string]model.Role{
+			"ops":                                   model.RoleOperator,
+			"cn=admins,ou=groups,dc=example,dc=org": model.RoleAdmin,
+		},
+	})
+
+	user, err := auth.Authenticate("alice", "alice-pass")
+	if err != nil {
+		t.Fatalf("Authenticate(alice): %v", err)
+	}
+	if user.Username != "alice" || user.Role != model.RoleOperator || user.AuthProvider != model.AuthProviderLDAP || user.ExternalID != ldapAliceDN {
+		t.Errorf("alice = %+v", user)
+	}
+	if !slices.Contains(server.bound(), ldapAliceDN) {
+		t.Error("alice's password was not checked with a bind")
+	}
+
+	// found through the group search, mapped by group DN
+	user, err = auth.Authenticate("bob", "bob-pass")
+	if err != nil {
+		t.Fatalf("Authenticate(bob): %v", err)
+	}
+	if user.Role != model.RoleAdmin {
+		t.Errorf("bob's role = %s, want %s", user.Role, model.RoleAdmin)
+	}
+
+	// the user is created once and reused
+	again, err := auth.Authenticate("alice", "alice-pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := userRepo.FindByUsername("alice")
+	if err != nil || stored == nil || again.ID != stored.ID {
+		t.Errorf("second login returned user %d, stored %+v (%v)", again.ID, stored, err)
+	}
+}
+
+func TestLDAPAuthenticatorRejectsInvalidCredentials(t *testing.T) {
+	server := newLDAPServer(t, nil, testDirectory...)
+	auth, userRepo := newTestLDAPAuthenticator(t, LDAPConfig{URL: server.url()})
+
+	local := &model.User{Username: "bob", Password: "local-pass", Role: model.RoleAdmin, AuthProvider: model.AuthProviderLocal}
+	if err := local.HashPassword(); err != nil {
+		t.Fatal(err)
+	}
+	if err := userRepo.Create(local); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, creds := range map[string][2]string{
+		"wrong password":        {"alice", "wrong"},
+		"empty password":        {"alice", ""},
+		"unknown user":          {"carol", "carol-pass"},
+		"other provider's user": {"bob", "bob-pass"},
+	} {
+		if _, err := auth.Authenticate(creds[0], creds[1]); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("%s: err = %v, want ErrInvalidCredentials", name, err)
+		}
+	}
+}
+
+func TestLoginFallsBackToLocalPasswords(t *testing.T) {
+	server := newLDAPServer(t, nil, testDirectory...)
+	db := newTestDB(t)
+	userRepo := repository.NewUserRepository(db)
+	ldapAuth, err := NewLDAPAuthenticator(LDAPConfig{
+		URL: server.url(), BaseDN: ldapBaseDN, BindDN: "cn=svc,dc=example,dc=org", BindPassword: "svc-pass",
+	}, userRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := newTestAuthService(db, ldapAuth, NewLocalAuthenticator(userRepo))
+
+	local := &model.User{Username: "carol", Password: "carol-pass", Role: model.RoleViewer, AuthProvider: model.AuthProviderLocal}
+	if err := local.HashPassword(); err != nil {
+		t.Fatal(err)
+	}
+	if err := userRepo.Create(local); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp, err := auth.Login("alice", "alice-pass", "127.0.0.1"); err != nil || resp.User.Username != "alice" {
+		t.Errorf("LDAP login: %+v, %v", resp, err)
+	}
+	if resp, err := auth.Login("carol", "carol-pass", "127.0.0.1"); err != nil || resp.User.Username != "carol" {
+		t.Errorf("local login: %+v, %v", resp, err)
+	}
+	if _, err := auth.Login("alice", "carol-pass", "127.0.0.1"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("login with a wrong password: err = %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestLDAPAuthenticatorTLS(t *testing.T) {
+	cert, caFile := newTestCertificate(t)
+	server := newLDAPServer(t, &tls.Config{Certificates: []tls.Certificate{cert}}, testDirectory...)
+
+	auth, _ := newTestLDAPAuthenticator(t, LDAPConfig{URL: server.url(), CAFile: caFile})
+	if _, err := auth.Authenticate("alice", "alice-pass"); err != nil {
+		t.Errorf("Authenticate over ldaps with the CA: %v", err)
+	}
+
+	untrusted, _ := newTestLDAPAuthenticator(t, LDAPConfig{URL: server.url(), Timeout: 2 * time.Second})
+	if _, err := untrusted.Authenticate("alice", "alice-pass"); err == nil || errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Authenticate over ldaps without the CA: err = %v, want a certificate error", err)
+	}
+}
+
+// newTestCertificate creates a self-signed certificate for 127.0.0.1 and
+// writes it to a PEM file.
+func newTestCertificate(t *testing.T) (tls.Certificate, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, caFile
+}
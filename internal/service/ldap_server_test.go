@@ -0,0 +1,210 @@
+package service
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// ldapEntry is an entry of the directory served by ldapServer.
+type ldapEntry struct {
+	dn       string
+	password string // simple bind password; empty entries cannot bind
+	attrs    map[string][]string
+}
+
+// ldapServer is an in-process LDAP server that answers simple binds and
+// searches with equality, presence, and and or filters, which is what
+// LDAPAuthenticator sends.
+type ldapServer struct {
+	listener net.Listener
+	tls      bool
+	entries  []ldapEntry
+
+	mu    sync.Mutex
+	binds []string // DNs of successful binds
+}
+
+// newLDAPServer serves entries on a local port, over TLS if tlsConfig is
+// set.
+func newLDAPServer(t *testing.T, tlsConfig *tls.Config, entries ...ldapEntry) *ldapServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	s := &ldapServer{listener: listener, tls: tlsConfig != nil, entries: entries}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *ldapServer) url() string {
+	if s.tls {
+		return "ldaps://" + s.listener.Addr().String()
+	}
+	return "ldap://" + s.listener.Addr().String()
+}
+
+// bound returns the DNs that bound successfully so far.
+func (s *ldapServer) bound() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.binds...)
+}
+
+func (s *ldapServer) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		if len(packet.Children) < 2 {
+			return
+		}
+		id := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			code := s.bind(packetString(op.Children[1]), packetString(op.Children[2]))
+			s.reply(conn, id, ldap.ApplicationBindResponse, code)
+		case ldap.ApplicationSearchRequest:
+			s.search(conn, id, op)
+		case ldap.ApplicationUnbindRequest:
+			return
+		default:
+			s.reply(conn, id, ldap.ApplicationExtendedResponse, ldap.LDAPResultUnwillingToPerform)
+		}
+	}
+}
+
+func (s *ldapServer) bind(dn, password string) uint16 {
+	if dn == "" && password == "" {
+		return ldap.LDAPResultSuccess
+	}
+	for _, entry := range s.entries {
+		if strings.EqualFold(entry.dn, dn) && entry.password != "" && entry.password == password {
+			s.mu.Lock()
+			s.binds = append(s.binds, entry.dn)
+			s.mu.Unlock()
+			return ldap.LDAPResultSuccess
+		}
+	}
+	return ldap.LDAPResultInvalidCredentials
+}
+
+func (s *ldapServer) search(w io.Writer, id int64, op *ber.Packet) {
+	base := packetString(op.Children[0])
+	filter := op.Children[6]
+	for _, entry := range s.entries {
+		if !hasSuffixFold(entry.dn, base) || !matchFilter(entry, filter) {
+			continue
+		}
+		result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "entry")
+		result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, "dn"))
+		attrs := ber.NewSequence("attributes")
+		for name, values := range entry.attrs {
+			attr := ber.NewSequence("attribute")
+			attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+			set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "values")
+			for _, value := range values {
+				set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "value"))
+			}
+			attr.AppendChild(set)
+			attrs.AppendChild(attr)
+		}
+		result.AppendChild(attrs)
+		s.write(w, id, result)
+	}
+	s.reply(w, id, ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess)
+}
+
+func (s *ldapServer) reply(w io.Writer, id int64, tag ber.Tag, code uint16) {
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "result")
+	result.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "resultCode"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "diagnosticMessage"))
+	s.write(w, id, result)
+}
+
+func (s *ldapServer) write(w io.Writer, id int64, op *ber.Packet) {
+	message := ber.NewSequence("message")
+	message.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "messageID"))
+	message.AppendChild(op)
+	w.Write(message.Bytes())
+}
+
+// matchFilter evaluates the filter kinds LDAPAuthenticator uses.
+func matchFilter(entry ldapEntry, filter *ber.Packet) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !matchFilter(entry, child) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if matchFilter(entry, child) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterPresent:
+		return len(entryValues(entry, packetString(filter))) > 0
+	case ldap.FilterEqualityMatch:
+		want := packetString(filter.Children[1])
+		for _, value := range entryValues(entry, packetString(filter.Children[0])) {
+			if strings.EqualFold(value, want) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+func entryValues(entry ldapEntry, name string) []string {
+	if strings.EqualFold(name, "objectClass") && entry.attrs["objectClass"] == nil {
+		return []string{"top"}
+	}
+	for attr, values := range entry.attrs {
+		if strings.EqualFold(attr, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+func packetString(p *ber.Packet) string {
+	if p.Data == nil {
+		return ""
+	}
+	return p.Data.String()
+}
+
+func hasSuffixFold(s, suffix string) bool {
+	return len(s) >= len(suffix) && strings.EqualFold(s[len(s)-len(suffix):], suffix)
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+
+	"gogo-scheduler/internal/storage"
+)
+
+// Log streams of a task.
+const (
+	StreamStdout   = "stdout"
+	StreamStderr   = "stderr"
+	StreamCombined = "combined"
+)
+
+var logStreams = []string{StreamStdout, StreamStderr, StreamCombined}
+
+// maxLineLength bounds how much of an unterminated line is held back before
+// it is written to the combined log anyway.
+const maxLineLength = 64 << 10
+
+// combinedTimeFormat is the timestamp prefix of each line in the combined log.
+const combinedTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// LogCapture collects stdout and stderr of a process separately and also
+// interleaves them, line by line, into a combined log where each line is
+// prefixed with its time and stream.
+type LogCapture struct {
+	Stdout   *storage.CappedBuffer
+	Stderr   *storage.CappedBuffer
+	Combined *storage.CappedBuffer
+
+	mu      sync.Mutex
+	partial map[string][]byte // unterminated line per stream
+}
+
+func newLogCapture(maxSize int) *LogCapture {
+	return &LogCapture{
+		Stdout:   storage.NewCappedBuffer(maxSize),
+		Stderr:   storage.NewCappedBuffer(maxSize),
+		Combined: storage.NewCappedBuffer(maxSize),
+		partial:  make(map[string][]byte),
+	}
+}
+
+// StdoutWriter returns the writer to use as the process's stdout.
+func (c *LogCapture) StdoutWriter() io.Writer {
+	return &streamWriter{capture: c, stream: StreamStdout, buf: c.Stdout}
+}
+
+// StderrWriter returns the writer to use as the process's stderr.
+func (c *LogCapture) StderrWriter() io.Writer {
+	return &streamWriter{capture: c, stream: StreamStderr, buf: c.Stderr}
+}
+
+// Flush writes any unterminated trailing lines to the combined log. Call it
+// once the process has exited.
+func (c *LogCapture) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, stream := range []string{StreamStdout, StreamStderr} {
+		if line := c.partial[stream]; len(line) > 0 {
+			c.writeLine(stream, line)
+			delete(c.partial, stream)
+		}
+	}
+}
+
+// Truncated reports whether any of the streams exceeded the size limit.
+func (c *LogCapture) Truncated() bool {
+	return c.Stdout.Truncated() || c.Stderr.Truncated() || c.Combined.Truncated()
+}
+
+func (c *LogCapture) buffer(stream string) *storage.CappedBuffer {
+	switch stream {
+	case StreamStdout:
+		return c.Stdout
+	case StreamStderr:
+		return c.Stderr
+	default:
+		return c.Combined
+	}
+}
+
+func (c *LogCapture) writeCombined(stream string, p []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := append(c.partial[stream], p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		c.writeLine(stream, data[:i])
+		data = data[i+1:]
+	}
+	if len(data) >= maxLineLength {
+		c.writeLine(stream, data)
+		data = nil
+	}
+	c.partial[stream] = append([]byte(nil), data...)
+}
+
+func (c *LogCapture) writeLine(stream string, line []byte) {
+	var b bytes.Buffer
+	b.WriteString(time.Now().Format(combinedTimeFormat))
+	b.WriteString(" [")
+	b.WriteString(stream)
+	b.WriteString("] ")
+	b.Write(line)
+	b.WriteByte('\n')
+	c.Combined.Write(b.Bytes())
+}
+
+type streamWriter struct {
+	capture *LogCapture
+	stream  string
+	buf     *storage.CappedBuffer
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	w.capture.writeCombined(w.stream, p)
+	return len(p), nil
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/storage"
+)
+
+// outputPreviewSize is how much of the end of stdout and stderr is kept on
+// the task row so task listings stay small.
+const outputPreviewSize = 4096
+
+// LogService stores task logs in a storage.Store instead of the database.
+// Every task has a stdout, a stderr and a combined log.
+type LogService struct {
+	store    storage.Store
+	compress bool
+	maxSize  int
+}
+
+// NewLogService creates a LogService. Logs larger than maxSize bytes keep
+// their head and tail only; 0 disables the limit.
+func NewLogService(store storage.Store, compress bool, maxSize int) *LogService {
+	return &LogService{store: store, compress: compress, maxSize: maxSize}
+}
+
+// NewCapture returns a LogCapture with the configured size limit.
+func (s *LogService) NewCapture() *LogCapture {
+	return newLogCapture(s.maxSize)
+}
+
+// Save writes the captured logs of a task to storage and records their
+// metadata and a short preview on the task. The caller persists the task.
+func (s *LogService) Save(ctx context.Context, task *model.Task, capture *LogCapture) error {
+	capture.Flush()
+
+	task.Output = preview(capture.Stdout.Bytes())
+	task.ErrorOutput = preview(capture.Stderr.Bytes())
+	task.LogSize = int64(len(capture.Combined.Bytes()))
+	task.StdoutSize = int64(len(capture.Stdout.Bytes()))
+	task.StderrSize = int64(len(capture.Stderr.Bytes()))
+	task.LogTruncated = capture.Truncated()
+	task.LogCompressed = s.compress
+
+	for _, stream := range logStreams {
+		payload, err := s.encode(capture.buffer(stream).Bytes())
+		if err != nil {
+			return err
+		}
+		key := logKey(task.ID, stream, task.LogCompressed)
+		if err := s.store.Put(ctx, key, bytes.NewReader(payload), int64(len(payload))); err != nil {
+			return err
+		}
+	}
+	task.LogStored = true
+	return nil
+}
+
+// Read returns up to limit bytes of one log stream of a task starting at
+// offset, plus the total size of that stream. A negative offset counts from
+// the end; a limit of 0 reads to the end. Only the requested range is read
+// from storage. Tasks from before log storage fall back to the previews on
+// the task.
+func (s *LogService) Read(ctx context.Context, task *model.Task, stream string, offset, limit int64) ([]byte, int64, error) {
+	if !task.LogStored {
+		data := []byte(task.Output)
+		if stream == StreamStderr {
+			data = []byte(task.ErrorOutput)
+		}
+		start, end := logRange(int64(len(data)), offset, limit)
+		return data[start:end], int64(len(data)), nil
+	}
+
+	total := storedSize(task, stream)
+	start, end := logRange(total, offset, limit)
+	if start == end {
+		return []byte{}, total, nil
+	}
+	data, err := s.load(ctx, logKey(task.ID, stream, task.LogCompressed), task.LogCompressed, start, end-start)
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, total, nil
+}
+
+// Delete removes the stored logs of the given tasks.
+func (s *LogService) Delete(ctx context.Context, taskIDs ...int64) error {
+	for _, id := range taskIDs {
+		for _, stream := range logStreams {
+			for _, compressed := range []bool{false, true} {
+				if err := s.store.Delete(ctx, logKey(id, stream, compressed)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// IsLogStream reports whether name is a valid log stream.
+func IsLogStream(name string) bool {
+	for _, stream := range logStreams {
+		if stream == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *LogService) encode(data []byte) ([]byte, error) {
+	if !s.compress {
+		return data, nil
+	}
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return gz.Bytes(), nil
+}
+
+// load reads length bytes of a stored log starting at offset. Uncompressed
+// logs are read with a ranged request; compressed logs are decompressed as a
+// stream up to the end of the range.
+func (s *LogService) load(ctx context.Context, key string, compressed bool, offset, length int64) ([]byte, error) {
+	if !compressed {
+		rc, err := s.store.GetRange(ctx, key, offset, length)
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(io.LimitReader(rc, length))
+	}
+
+	rc, err := s.store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	gz, err := gzip.NewReader(rc)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	if _, err := io.CopyN(io.Discard, gz, offset); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return io.ReadAll(io.LimitReader(gz, length))
+}
+
+// logRange clamps a requested offset and limit to a stream of total bytes
+// and returns the start and end of the range.
+func logRange(total, offset, limit int64) (int64, int64) {
+	if offset < 0 {
+		offset = max(total+offset, 0)
+	}
+	if offset > total {
+		offset = total
+	}
+	end := total
+	if limit > 0 && offset+limit < total {
+		end = offset + limit
+	}
+	return offset, end
+}
+
+// storedSize returns the size of a stored log stream as recorded on the
+// task.
+func storedSize(task *model.Task, stream string) int64 {
+	switch stream {
+	case StreamStdout:
+		return task.StdoutSize
+	case StreamStderr:
+		return task.StderrSize
+	default:
+		return task.LogSize
+	}
+}
+
+func logKey(taskID int64, stream string, compressed bool) string {
+	key := fmt.Sprintf("tasks/%d/%s.log", taskID, stream)
+	if compressed {
+		key += ".gz"
+	}
+	return key
+}
+
+func preview(data []byte) string {
+	if len(data) > outputPreviewSize {
+		data = data[len(data)-outputPreviewSize:]
+	}
+	return string(data)
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/storage"
+)
+
+func TestLogServiceRead(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		t.Run(fmt.Sprintf("compress=%v", compress), func(t *testing.T) {
+			store, err := storage.NewLocalStore(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			logs := NewLogService(store, compress, 0)
+			ctx := context.Background()
+
+			capture := logs.NewCapture()
+			fmt.Fprint(capture.Stdout, "0123456789")
+			task := &model.Task{ID: 7}
+			if err := logs.Save(ctx, task, capture); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+
+			for _, tc := range []struct {
+				offset, limit int64
+				want          string
+			}{
+				{0, 0, "0123456789"},
+				{2, 3, "234"},
+				{-4, 0, "6789"},
+				{-20, 2, "01"},
+				{8, 10, "89"},
+				{12, 0, ""},
+			} {
+				data, total, err := logs.Read(ctx, task, StreamStdout, tc.offset, tc.limit)
+				if err != nil {
+					t.Fatalf("Read(%d, %d): %v", tc.offset, tc.limit, err)
+				}
+				if string(data) != tc.want || total != 10 {
+					t.Errorf("Read(%d, %d) = %q, %d; want %q, 10", tc.offset, tc.limit, data, total, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestLogServiceReadPreview(t *testing.T) {
+	logs := NewLogService(nil, false, 0)
+	task := &model.Task{Output: "out", ErrorOutput: strings.Repeat("e", 5)}
+
+	data, total, err := logs.Read(context.Background(), task, StreamStderr, -2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "ee" || total != 5 {
+		t.Errorf("Read = %q, %d; want %q, 5", data, total, "ee")
+	}
+}
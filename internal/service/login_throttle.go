@@ -0,0 +1,160 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxThrottleEntries bounds the number of tracked usernames and of tracked
+// addresses. Expired entries are pruned first; if all are still active,
+// the one whose last failure is oldest is evicted.
+const maxThrottleEntries = 10000
+
+// ThrottleConfig controls how failed logins slow down further attempts.
+// After the free attempts every failure doubles the delay before the next
+// attempt, up to MaxDelay; after MaxAttempts failures the username or
+// address is locked out. Failures are forgotten once Lockout has passed
+// since the last one.
+type ThrottleConfig struct {
+	FreeAttempts   int // failures per username before delays start
+	MaxAttempts    int // failures per username before lockout
+	IPFreeAttempts int
+	IPMaxAttempts  int
+	BaseDelay      time.Duration
+	MaxDelay       time.Duration
+	Lockout        time.Duration
+}
+
+func DefaultThrottleConfig() ThrottleConfig {
+	return ThrottleConfig{
+		FreeAttempts:   3,
+		MaxAttempts:    10,
+		IPFreeAttempts: 20,
+		IPMaxAttempts:  100,
+		BaseDelay:      time.Second,
+		MaxDelay:       30 * time.Second,
+		Lockout:        15 * time.Minute,
+	}
+}
+
+// LoginThrottledError is returned by Login while a username or client address
+// has to wait before trying again.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+type failedLogins struct {
+	count int
+	last  time.Time
+}
+
+// LoginThrottle tracks failed logins per username and per client address.
+type LoginThrottle struct {
+	cfg        ThrottleConfig
+	maxEntries int
+	mu         sync.Mutex
+	users      map[string]*failedLogins
+	ips        map[string]*failedLogins
+}
+
+func NewLoginThrottle(cfg ThrottleConfig) *LoginThrottle {
+	return &LoginThrottle{
+		cfg:        cfg,
+		maxEntries: maxThrottleEntries,
+		users:      make(map[string]*failedLogins),
+		ips:        make(map[string]*failedLogins),
+	}
+}
+
+// Check returns a LoginThrottledError if the username or address must wait.
+func (t *LoginThrottle) Check(username, ip string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	wait := t.wait(t.users[strings.ToLower(username)], t.cfg.FreeAttempts, t.cfg.MaxAttempts, now)
+	if ip != "" {
+		wait = max(wait, t.wait(t.ips[ip], t.cfg.IPFreeAttempts, t.cfg.IPMaxAttempts, now))
+	}
+	if wait > 0 {
+		return &LoginThrottledError{RetryAfter: wait}
+	}
+	return nil
+}
+
+func (t *LoginThrottle) Fail(username, ip string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	t.record(t.users, strings.ToLower(username), now)
+	if ip != "" {
+		t.record(t.ips, ip, now)
+	}
+}
+
+// Succeed clears the failures of the username. The address keeps its count
+// so that one valid account cannot be used to reset it.
+func (t *LoginThrottle) Succeed(username string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.users, strings.ToLower(username))
+}
+
+func (t *LoginThrottle) wait(f *failedLogins, free, limit int, now time.Time) time.Duration {
+	if f == nil || f.count <= free || t.expired(f, now) {
+		return 0
+	}
+
+	delay := t.cfg.Lockout
+	if f.count < limit {
+		delay = t.cfg.BaseDelay << min(f.count-free-1, 30)
+		if delay <= 0 || delay > t.cfg.MaxDelay {
+			delay = t.cfg.MaxDelay
+		}
+	}
+	return max(f.last.Add(delay).Sub(now), 0)
+}
+
+func (t *LoginThrottle) record(entries map[string]*failedLogins, key string, now time.Time) {
+	f, ok := entries[key]
+	if !ok || t.expired(f, now) {
+		if len(entries) >= t.maxEntries {
+			t.prune(entries, now)
+		}
+		f = &failedLogins{}
+		entries[key] = f
+	}
+	f.count++
+	f.last = now
+}
+
+func (t *LoginThrottle) expired(f *failedLogins, now time.Time) bool {
+	return now.Sub(f.last) > t.cfg.Lockout
+}
+
+// prune removes the expired entries, and then the least recently failed
+// ones until there is room for a new entry.
+func (t *LoginThrottle) prune(entries map[string]*failedLogins, now time.Time) {
+	for key, f := range entries {
+		if t.expired(f, now) {
+			delete(entries, key)
+		}
+	}
+	for len(entries) >= t.maxEntries {
+		var oldest string
+		var last *failedLogins
+		for key, f := range entries {
+			if last == nil || f.last.Before(last.last) {
+				oldest, last = key, f
+			}
+		}
+		delete(entries, oldest)
+	}
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestLoginThrottleBoundsEntries(t *testing.T) {
+	throttle := NewLoginThrottle(DefaultThrottleConfig())
+	throttle.maxEntries = 100
+
+	// a spray of usernames from many addresses inside the lockout window
+	for i := 0; i < 1000; i++ {
+		throttle.Fail(fmt.Sprintf("user%d", i), fmt.Sprintf("10.0.%d.%d", i/256, i%256))
+	}
+	if len(throttle.users) > 100 || len(throttle.ips) > 100 {
+		t.Fatalf("throttle tracks %d usernames and %d addresses, want at most 100", len(throttle.users), len(throttle.ips))
+	}
+	// the most recent failures are the ones kept
+	if throttle.users["user999"] == nil || throttle.users["user0"] != nil {
+		t.Error("evicted a recent failure instead of the oldest one")
+	}
+}
+
+func TestLoginThrottleDelaysAndLocksOut(t *testing.T) {
+	cfg := DefaultThrottleConfig()
+	throttle := NewLoginThrottle(cfg)
+
+	for i := 0; i < cfg.FreeAttempts; i++ {
+		throttle.Fail("alice", "")
+	}
+	if err := throttle.Check("alice", ""); err != nil {
+		t.Fatalf("Check after the free attempts: %v", err)
+	}
+	throttle.Fail("Alice", "")
+	err, ok := throttle.Check("ALICE", "").(*LoginThrottledError)
+	if !ok || err.RetryAfter <= 0 || err.RetryAfter > cfg.BaseDelay {
+		t.Fatalf("Check after one more failure = %v, want a delay of up to %s", err, cfg.BaseDelay)
+	}
+
+	for i := cfg.FreeAttempts + 1; i < cfg.MaxAttempts; i++ {
+		throttle.Fail("alice", "")
+	}
+	err, ok = throttle.Check("alice", "").(*LoginThrottledError)
+	if !ok || err.RetryAfter < cfg.Lockout-time.Second {
+		t.Fatalf("Check after %d failures = %v, want the lockout", cfg.MaxAttempts, err)
+	}
+
+	throttle.Succeed("alice")
+	if err := throttle.Check("alice", ""); err != nil {
+		t.Errorf("Check after a successful login: %v", err)
+	}
+}
@@ -0,0 +1,460 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gogo-scheduler/internal/logging"
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var ErrChannelNotFound = errors.New("notification channel not found")
+
+// EventTest is the event of test notifications sent from the API.
+const EventTest = "test"
+
+// Sender delivers notifications through one type of channel.
+type Sender interface {
+	// Send delivers the notification once to the recipients, which only
+	// email channels use. It returns the status code of the destination's
+	// response, if there was one.
+	Send(ctx context.Context, channel *model.NotificationChannel, recipients []string, n *model.Notification) (int, error)
+}
+
+// RetryConfig controls how failed deliveries are retried. The delay doubles
+// after every attempt.
+type RetryConfig struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{MaxAttempts: 5, BaseDelay: 2 * time.Second, MaxDelay: time.Minute}
+}
+
+// NotificationService manages notification channels and delivers task
+// events to the channels scripts subscribe to.
+type NotificationService struct {
+	repo        *repository.NotificationRepository
+	projectRepo *repository.ProjectRepository
+	senders     map[string]Sender
+	retry       RetryConfig
+	timeout     time.Duration // per attempt
+	publicURL   string        // links notifications to their tasks if set
+
+	// ctx is cancelled by Stop, which ends the retries of deliveries
+	ctx        context.Context
+	stop       context.CancelFunc
+	deliveries sync.WaitGroup
+}
+
+// NewNotificationService returns the service. Webhooks may only reach
+// loopback, private and link-local addresses in allowedNetworks.
+func NewNotificationService(repo *repository.NotificationRepository, projectRepo *repository.ProjectRepository, retry RetryConfig, publicURL string, allowedNetworks []*net.IPNet) *NotificationService {
+	client := newWebhookClient(allowedNetworks)
+	ctx, stop := context.WithCancel(context.Background())
+	return &NotificationService{
+		repo:        repo,
+		projectRepo: projectRepo,
+		senders: map[string]Sender{
+			model.ChannelWebhook: NewWebhookSender(client),
+			model.ChannelEmail:   NewEmailSender(),
+		},
+		retry:     retry,
+		timeout:   30 * time.Second,
+		publicURL: strings.TrimSuffix(publicURL, "/"),
+		ctx:       ctx,
+		stop:      stop,
+	}
+}
+
+// Stop cancels the deliveries in progress and waits until they have
+// recorded their last attempt. Deliveries that were still to be retried
+// stay pending until repository.FailPendingDeliveries on the next start.
+func (s *NotificationService) Stop() {
+	s.stop()
+	s.deliveries.Wait()
+}
+
+// ListChannels returns the channels of the project with their credentials
+// redacted.
+func (s *NotificationService) ListChannels(projectID int64) ([]model.NotificationChannel, error) {
+	channels, err := s.repo.ListChannels(projectID)
+	for i := range channels {
+		channels[i] = *channels[i].Redacted()
+	}
+	return channels, err
+}
+
+// GetChannel returns a channel of the project with its credentials
+// redacted.
+func (s *NotificationService) GetChannel(projectID, id int64) (*model.NotificationChannel, error) {
+	channel, err := s.channel(projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	return channel.Redacted(), nil
+}
+
+// channel returns a channel of the project.
+func (s *NotificationService) channel(projectID, id int64) (*model.NotificationChannel, error) {
+	channel, err := s.repo.GetChannel(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) || err == nil && channel.ProjectID != projectID {
+		return nil, ErrChannelNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	channel.HasSecret = channel.Secret != ""
+	return channel, nil
+}
+
+func (s *NotificationService) CreateChannel(projectID int64, req model.NotificationChannelRequest) (*model.NotificationChannel, error) {
+	channel := &model.NotificationChannel{ProjectID: projectID, Enabled: true}
+	if err := s.applyChannelRequest(channel, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateChannel(channel); err != nil {
+		return nil, err
+	}
+	return channel.Redacted(), nil
+}
+
+func (s *NotificationService) UpdateChannel(projectID, id int64, req model.NotificationChannelRequest) (*model.NotificationChannel, error) {
+	channel, err := s.channel(projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	// redacted values sent back unchanged keep the stored ones
+	if req.Webhook != nil && channel.Webhook != nil {
+		if err := req.Webhook.Restore(channel.Webhook); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.applyChannelRequest(channel, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateChannel(channel); err != nil {
+		return nil, err
+	}
+	return channel.Redacted(), nil
+}
+
+func (s *NotificationService) applyChannelRequest(channel *model.NotificationChannel, req model.NotificationChannelRequest) error {
+	if _, ok := s.senders[req.Type]; !ok {
+		return fmt.Errorf("unsupported channel type: %s", req.Type)
+	}
+	switch req.Type {
+	case model.ChannelWebhook:
+		if req.Webhook == nil {
+			return errors.New("webhook settings are required")
+		}
+		if u, err := url.Parse(req.Webhook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("webhook url must be an http or https URL")
+		}
+		if _, ok := chatFormats[req.Webhook.Format]; req.Webhook.Format != "" && !ok {
+			return fmt.Errorf("unsupported webhook format: %s", req.Webhook.Format)
+		}
+		req.Email = nil
+	case model.ChannelEmail:
+		if err := validateEmailConfig(req.Email); err != nil {
+			return err
+		}
+		req.Webhook = nil
+	}
+
+	channel.Name = req.Name
+	channel.Type = req.Type
+	channel.Webhook = req.Webhook
+	channel.Email = req.Email
+	if req.Enabled != nil {
+		channel.Enabled = *req.Enabled
+	}
+	if req.Secret != nil {
+		channel.Secret = *req.Secret
+	}
+	channel.HasSecret = channel.Secret != ""
+	return nil
+}
+
+func validateEmailConfig(cfg *model.EmailConfig) error {
+	if cfg == nil {
+		return errors.New("email settings are required")
+	}
+	if cfg.Host == "" {
+		return errors.New("smtp host is required")
+	}
+	if cfg.Port < 1 || cfg.Port > 65535 {
+		return errors.New("smtp port must be between 1 and 65535")
+	}
+	if _, err := mail.ParseAddress(cfg.From); err != nil {
+		return fmt.Errorf("invalid sender: %w", err)
+	}
+	if _, err := parseAddresses(cfg.To); err != nil {
+		return err
+	}
+	_, _, err := parseEmailTemplates(cfg)
+	return err
+}
+
+func (s *NotificationService) DeleteChannel(projectID, id int64) error {
+	if _, err := s.channel(projectID, id); err != nil {
+		return err
+	}
+	return s.repo.DeleteChannel(id)
+}
+
+// TestChannel sends a test notification once and returns its delivery.
+func (s *NotificationService) TestChannel(projectID, id int64) (*model.NotificationDelivery, error) {
+	channel, err := s.channel(projectID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	n := &model.Notification{
+		Event:     EventTest,
+		Timestamp: now,
+		Task:      model.NotificationTask{Name: "test", Status: "success", StartTime: &now, EndTime: &now, Output: "test notification"},
+		Script:    model.NotificationScript{Name: channel.Name, ProjectID: projectID},
+	}
+	delivery := &model.NotificationDelivery{ChannelID: channel.ID, Event: EventTest, Recipients: recipients(channel, nil), Status: "pending"}
+	if err := s.repo.CreateDelivery(delivery); err != nil {
+		return nil, err
+	}
+	s.attempt(channel, n, delivery)
+	if delivery.Status == "pending" {
+		delivery.Status = "failed"
+	}
+	if err := s.repo.UpdateDelivery(delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+func (s *NotificationService) ListDeliveries(projectID, channelID int64, page, pageSize int) (*model.PageResult[model.NotificationDelivery], error) {
+	if _, err := s.channel(projectID, channelID); err != nil {
+		return nil, err
+	}
+	deliveries, total, err := s.repo.ListDeliveries(channelID, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &model.PageResult[model.NotificationDelivery]{Items: deliveries, Total: total, Page: page, PageSize: pageSize}, nil
+}
+
+func (s *NotificationService) ListSubscriptions(scriptID int64) ([]model.ScriptNotification, error) {
+	return s.repo.ListSubscriptions(scriptID)
+}
+
+// SetSubscriptions replaces the channels a script notifies. The channels
+// must belong to the script's project. Subscriptions to email channels
+// without recipients use the channel's recipients.
+func (s *NotificationService) SetSubscriptions(script *model.Script, reqs []model.ScriptNotificationRequest) ([]model.ScriptNotification, error) {
+	subscriptions := make([]model.ScriptNotification, 0, len(reqs))
+	for _, req := range reqs {
+		channel, err := s.channel(script.ProjectID, req.ChannelID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %d", err, req.ChannelID)
+		}
+		if channel.Type != model.ChannelEmail && len(req.Recipients) > 0 {
+			return nil, fmt.Errorf("channel %d does not take recipients", req.ChannelID)
+		}
+		if _, err := parseAddresses(req.Recipients); err != nil {
+			return nil, err
+		}
+		if channel.Type == model.ChannelEmail && len(recipients(channel, req.Recipients)) == 0 {
+			return nil, fmt.Errorf("channel %d has no recipients; recipients are required", req.ChannelID)
+		}
+		if len(req.Events) == 0 {
+			return nil, errors.New("events must not be empty")
+		}
+		for _, event := range req.Events {
+			if !slices.Contains(model.TaskEvents, event) {
+				return nil, fmt.Errorf("unknown event: %s", event)
+			}
+		}
+		subscriptions = append(subscriptions, model.ScriptNotification{ScriptID: script.ID, ChannelID: req.ChannelID, Events: req.Events, Recipients: req.Recipients})
+	}
+
+	if err := s.repo.ReplaceSubscriptions(script.ID, subscriptions); err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// Notify sends a task event to the enabled channels the script subscribes
+// to it on. Deliveries are retried in the background.
+func (s *NotificationService) Notify(ctx context.Context, event string, task *model.Task, script *model.Script) {
+	subscriptions, err := s.repo.ListSubscriptions(script.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "loading notification subscriptions failed", "error", err)
+		return
+	}
+
+	var n *model.Notification
+	for _, subscription := range subscriptions {
+		if !slices.Contains(subscription.Events, event) {
+			continue
+		}
+		if n == nil {
+			n = newNotification(event, task, script)
+			n.Task.URL = s.taskURL(ctx, task)
+		}
+		channel, err := s.repo.GetChannel(subscription.ChannelID)
+		if err != nil {
+			slog.ErrorContext(ctx, "loading notification channel failed", "channel_id", subscription.ChannelID, "error", err)
+			continue
+		}
+		if !channel.Enabled {
+			continue
+		}
+
+		delivery := &model.NotificationDelivery{ChannelID: channel.ID, TaskID: task.ID, Event: event, Recipients: recipients(channel, subscription.Recipients), Status: "pending"}
+		if err := s.repo.CreateDelivery(delivery); err != nil {
+			slog.ErrorContext(ctx, "recording notification delivery failed", "channel_id", channel.ID, "error", err)
+			continue
+		}
+		s.deliveries.Add(1)
+		go s.deliver(logging.With(ctx, "channel_id", channel.ID, "delivery_id", delivery.ID), channel, n, delivery)
+	}
+}
+
+// deliver sends a notification until it succeeds, fails for good or runs
+// out of attempts, recording every attempt. It stops retrying when the
+// service is stopped.
+func (s *NotificationService) deliver(ctx context.Context, channel *model.NotificationChannel, n *model.Notification, delivery *model.NotificationDelivery) {
+	defer s.deliveries.Done()
+
+	delay := s.retry.BaseDelay
+	for {
+		s.attempt(channel, n, delivery)
+		if delivery.Status == "pending" && delivery.Attempts >= s.retry.MaxAttempts {
+			delivery.Status = "failed"
+		}
+		if delivery.Status == "failed" {
+			slog.WarnContext(ctx, "notification failed", "event", delivery.Event, "attempts", delivery.Attempts, "error", delivery.Error)
+		}
+		if err := s.repo.UpdateDelivery(delivery); err != nil {
+			slog.ErrorContext(ctx, "recording notification delivery failed", "error", err)
+		}
+		if delivery.Status != "pending" {
+			return
+		}
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, s.retry.MaxDelay)
+	}
+}
+
+// attempt sends a notification once and records the outcome on the
+// delivery. It stays pending if the attempt failed in a way that retrying
+// may fix, and fails otherwise.
+func (s *NotificationService) attempt(channel *model.NotificationChannel, n *model.Notification, delivery *model.NotificationDelivery) {
+	ctx, cancel := context.WithTimeout(s.ctx, s.timeout)
+	defer cancel()
+
+	delivery.Attempts++
+	status, err := s.senders[channel.Type].Send(ctx, channel, delivery.Recipients, n)
+	delivery.ResponseStatus = status
+	if err != nil {
+		delivery.Error = err.Error()
+		if !retryable(channel.Type, status, err) {
+			delivery.Status = "failed"
+		}
+		return
+	}
+	delivery.Status = "delivered"
+	delivery.Error = ""
+}
+
+// retryable reports whether a failed attempt may succeed later: it failed
+// on the network, or the destination was unavailable or asked to slow down
+// with an HTTP 5xx or 429 response or an SMTP 4xx reply. Other rejections
+// and invalid requests are final.
+func retryable(channelType string, status int, err error) bool {
+	if status == 0 {
+		if errors.Is(err, ErrDestinationNotAllowed) {
+			return false
+		}
+		var netErr net.Error
+		return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+	}
+	if channelType == model.ChannelEmail {
+		return status >= 400 && status < 500
+	}
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// taskURL returns the API URL of a task, or "" if the public URL of the
+// server is not configured.
+func (s *NotificationService) taskURL(ctx context.Context, task *model.Task) string {
+	if s.publicURL == "" {
+		return ""
+	}
+	project, err := s.projectRepo.GetByID(task.ProjectID)
+	if err != nil {
+		slog.ErrorContext(ctx, "loading project of notification failed", "project_id", task.ProjectID, "error", err)
+		return ""
+	}
+	return fmt.Sprintf("%s/api/projects/%s/tasks/%d", s.publicURL, url.PathEscape(project.Slug), task.ID)
+}
+
+// recipients returns the recipients of a subscription to an email channel,
+// falling back to the channel's recipients.
+func recipients(channel *model.NotificationChannel, subscribed []string) []string {
+	if channel.Type != model.ChannelEmail {
+		return nil
+	}
+	if len(subscribed) > 0 {
+		return subscribed
+	}
+	return channel.Email.To
+}
+
+func newNotification(event string, task *model.Task, script *model.Script) *model.Notification {
+	n := &model.Notification{
+		Event:     event,
+		Timestamp: time.Now(),
+		Task: model.NotificationTask{
+			ID:          task.ID,
+			Name:        task.Name,
+			Status:      task.Status,
+			StartTime:   task.StartTime,
+			EndTime:     task.EndTime,
+			Error:       task.Error,
+			Output:      task.Output,
+			ErrorOutput: task.ErrorOutput,
+			RequestedBy: task.RequestedBy,
+		},
+		Script: model.NotificationScript{
+			ID:        script.ID,
+			Name:      script.Name,
+			Type:      script.Type,
+			ProjectID: script.ProjectID,
+		},
+	}
+	if task.StartTime != nil && task.EndTime != nil {
+		n.Task.DurationMs = task.EndTime.Sub(*task.StartTime).Milliseconds()
+	}
+	return n
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"errors"
+	"regexp"
+
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var ErrProjectNotFound = errors.New("project not found")
+
+// ErrQuotaChangeDenied is returned when a project admin who is not a global
+// administrator tries to change the project's run limit.
+var ErrQuotaChangeDenied = errors.New("only administrators can change max_concurrent_runs")
+
+var projectSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+type ProjectService struct {
+	projectRepo *repository.ProjectRepository
+	userRepo    *repository.UserRepository
+}
+
+func NewProjectService(projectRepo *repository.ProjectRepository, userRepo *repository.UserRepository) *ProjectService {
+	return &ProjectService{projectRepo: projectRepo, userRepo: userRepo}
+}
+
+// Resolve returns the project with the given slug and sets its Role to the
+// user's role in it. Projects the user is not a member of are reported as
+// not found.
+func (s *ProjectService) Resolve(slug string, user *model.User) (*model.Project, error) {
+	project, err := s.projectRepo.GetBySlug(slug)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrProjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	role, err := s.roleIn(project, user)
+	if err != nil {
+		return nil, err
+	}
+	if role == "" {
+		return nil, ErrProjectNotFound
+	}
+	project.Role = role
+	return project, nil
+}
+
+// roleIn returns the user's role in the project, or "" if they are not a
+// member. Administrators are administrators of every project, and everyone
+// belongs to the default project with their global role unless they were
+// given another one.
+func (s *ProjectService) roleIn(project *model.Project, user *model.User) (model.Role, error) {
+	if user.Role == model.RoleAdmin {
+		return model.RoleAdmin, nil
+	}
+	member, err := s.projectRepo.GetMember(project.ID, user.ID)
+	if err != nil {
+		return "", err
+	}
+	if member != nil {
+		return member.Role, nil
+	}
+	if project.Slug == model.DefaultProjectSlug {
+		return user.Role, nil
+	}
+	return "", nil
+}
+
+// ListProjects returns the projects the user belongs to.
+func (s *ProjectService) ListProjects(user *model.User) ([]model.Project, error) {
+	var projects []model.Project
+	var err error
+	if user.Role == model.RoleAdmin {
+		projects, err = s.projectRepo.List()
+	} else {
+		projects, err = s.projectRepo.ListByMember(user.ID)
+		if err == nil && !containsSlug(projects, model.DefaultProjectSlug) {
+			var project *model.Project
+			if project, err = s.projectRepo.GetBySlug(model.DefaultProjectSlug); err == nil {
+				projects = append([]model.Project{*project}, projects...)
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range projects {
+		if projects[i].Role, err = s.roleIn(&projects[i], user); err != nil {
+			return nil, err
+		}
+	}
+	return projects, nil
+}
+
+func containsSlug(projects []model.Project, slug string) bool {
+	for _, project := range projects {
+		if project.Slug == slug {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *ProjectService) CreateProject(req model.CreateProjectRequest) (*model.Project, error) {
+	if !projectSlugPattern.MatchString(req.Slug) {
+		return nil, errors.New("slug must be lowercase letters, digits and dashes")
+	}
+	if req.MaxConcurrentRuns < 0 {
+		return nil, errors.New("max_concurrent_runs must not be negative")
+	}
+
+	project := &model.Project{
+		Slug:              req.Slug,
+		Name:              req.Name,
+		Description:       req.Description,
+		MaxConcurrentRuns: req.MaxConcurrentRuns,
+	}
+	if err := s.projectRepo.Create(project); err != nil {
+		return nil, errors.New("project slug already exists")
+	}
+	project.Role = model.RoleAdmin
+	return project, nil
+}
+
+// UpdateProject changes a project on behalf of the user with the given ID.
+// Its run limit can only be changed by global administrators, so that
+// project admins cannot lift their own quota.
+func (s *ProjectService) UpdateProject(project *model.Project, userID int64, req model.UpdateProjectRequest) (*model.Project, error) {
+	if req.MaxConcurrentRuns < 0 {
+		return nil, errors.New("max_concurrent_runs must not be negative")
+	}
+	if req.MaxConcurrentRuns != project.MaxConcurrentRuns {
+		// the user in the request holds the project role, so check the
+		// global one
+		user, err := s.userRepo.FindByID(uint(userID))
+		if err != nil {
+			return nil, err
+		}
+		if user.Role != model.RoleAdmin {
+			return nil, ErrQuotaChangeDenied
+		}
+	}
+
+	updated := *project
+	updated.Name = req.Name
+	updated.Description = req.Description
+	updated.MaxConcurrentRuns = req.MaxConcurrentRuns
+	if err := s.projectRepo.Update(&updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteProject deletes an empty project. The default project cannot be
+// deleted.
+func (s *ProjectService) DeleteProject(project *model.Project) error {
+	if project.Slug == model.DefaultProjectSlug {
+		return errors.New("the default project cannot be deleted")
+	}
+	return s.projectRepo.Delete(project.ID)
+}
+
+func (s *ProjectService) ListMembers(projectID int64) ([]model.ProjectMember, error) {
+	return s.projectRepo.ListMembers(projectID)
+}
+
+// SetMember adds a user to a project or changes their role in it.
+func (s *ProjectService) SetMember(projectID, userID int64, role model.Role) (*model.ProjectMember, error) {
+	if !role.Valid() {
+		return nil, errors.New("invalid role")
+	}
+	user, err := s.userRepo.FindByID(uint(userID))
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	member, err := s.projectRepo.GetMember(projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		member = &model.ProjectMember{ProjectID: projectID, UserID: userID}
+	}
+	member.Role = role
+	if err := s.projectRepo.SaveMember(member); err != nil {
+		return nil, err
+	}
+	member.Username = user.Username
+	return member, nil
+}
+
+func (s *ProjectService) RemoveMember(projectID, userID int64) error {
+	found, err := s.projectRepo.RemoveMember(projectID, userID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("member not found")
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/repository"
+)
+
+type RetentionService struct {
+	repo      *repository.RetentionRepository
+	taskRepo  *repository.TaskRepository
+	logs      *LogService
+	artifacts *ArtifactService
+	scripts   *ScriptService
+	mu        sync.Mutex // serializes runs of the background job and manual triggers
+	loop      heartbeat  // runs of Start
+}
+
+func NewRetentionService(repo *repository.RetentionRepository, taskRepo *repository.TaskRepository, logs *LogService, artifacts *ArtifactService, scripts *ScriptService) *RetentionService {
+	return &RetentionService{repo: repo, taskRepo: taskRepo, logs: logs, artifacts: artifacts, scripts: scripts}
+}
+
+// Start applies the retention policies every interval until ctx is done.
+func (s *RetentionService) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.loop.beat()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.loop.beat()
+			report, err := s.Run(0, false)
+			if err != nil {
+				slog.ErrorContext(ctx, "applying retention policies failed", "error", err)
+				continue
+			}
+			if len(report.ExpiredTasks) > 0 || report.PurgedTasks > 0 || report.PurgedScripts > 0 {
+				slog.InfoContext(ctx, "applied retention policies", "expired_tasks", len(report.ExpiredTasks),
+					"purged_tasks", report.PurgedTasks, "purged_scripts", report.PurgedScripts)
+			}
+		}
+	}
+}
+
+// LastRun returns when the loop of Start last ran, or the zero time if it
+// is not running.
+func (s *RetentionService) LastRun() time.Time {
+	return s.loop.Last()
+}
+
+// Run applies the retention policies once to the tasks and scripts of a
+// project, or of all projects for projectID 0. With dryRun nothing is
+// removed and the report lists what would have been. The database is only
+// vacuumed by runs over all projects.
+func (s *RetentionService) Run(projectID int64, dryRun bool) (*model.RetentionReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := &model.RetentionReport{
+		DryRun:       dryRun,
+		ExpiredTasks: []int64{},
+		StartedAt:    time.Now(),
+	}
+
+	global, err := s.GetPolicy(0)
+	if err != nil {
+		return nil, err
+	}
+
+	scriptIDs, err := s.taskRepo.ScriptIDs(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, scriptID := range scriptIDs {
+		policy, err := s.repo.GetByScriptID(scriptID)
+		if err != nil {
+			return nil, err
+		}
+		if policy == nil {
+			policy = global
+		}
+
+		tasks, err := s.taskRepo.ListFinished(scriptID)
+		if err != nil {
+			return nil, err
+		}
+		report.ExpiredTasks = append(report.ExpiredTasks, expiredTasks(policy, tasks, report.StartedAt)...)
+	}
+
+	if !dryRun {
+		if err := s.removeTasks(report.ExpiredTasks); err != nil {
+			return nil, err
+		}
+	}
+
+	if global.PurgeDeleted {
+		deleted, err := s.taskRepo.DeletedIDs(projectID)
+		if err != nil {
+			return nil, err
+		}
+		if !dryRun {
+			if err := s.removeTasks(deleted); err != nil {
+				return nil, err
+			}
+		}
+		report.PurgedTasks = int64(len(deleted))
+
+		if report.PurgedScripts, err = s.repo.PurgeDeletedScripts(projectID, dryRun); err != nil {
+			return nil, err
+		}
+	}
+
+	removed := len(report.ExpiredTasks) > 0 || report.PurgedTasks > 0 || report.PurgedScripts > 0
+	if global.Vacuum && removed && !dryRun && projectID == 0 {
+		if err := s.repo.Vacuum(); err != nil {
+			return nil, err
+		}
+		report.Vacuumed = true
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// GetPolicy returns the policy for a script, or the global one for ID 0.
+// A missing global policy is returned as an empty policy that keeps
+// everything.
+func (s *RetentionService) GetPolicy(scriptID int64) (*model.RetentionPolicy, error) {
+	policy, err := s.repo.GetByScriptID(scriptID)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil && scriptID == 0 {
+		policy = &model.RetentionPolicy{}
+	}
+	return policy, nil
+}
+
+// AuthorizeScript checks that a script belongs to the scope's project and
+// that the user has at least the given level on it, before its policy is
+// read or changed.
+func (s *RetentionService) AuthorizeScript(ctx context.Context, scope model.ProjectScope, scriptID int64, level model.Permission) error {
+	_, err := s.scripts.script(ctx, scope, scriptID, level)
+	return err
+}
+
+func (s *RetentionService) UpdatePolicy(scriptID int64, req model.RetentionPolicyRequest) (*model.RetentionPolicy, error) {
+	policy, err := s.repo.GetByScriptID(scriptID)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		policy = &model.RetentionPolicy{ScriptID: scriptID}
+	}
+
+	policy.KeepLastN = req.KeepLastN
+	policy.KeepDays = req.KeepDays
+	policy.KeepFailedDays = req.KeepFailedDays
+	if scriptID == 0 {
+		policy.PurgeDeleted = req.PurgeDeleted
+		policy.Vacuum = req.Vacuum
+	}
+
+	err = s.repo.Save(policy)
+	return policy, err
+}
+
+func (s *RetentionService) DeletePolicy(scriptID int64) error {
+	return s.repo.DeleteByScriptID(scriptID)
+}
+
+// removeTasks hard-deletes tasks together with their logs and artifacts.
+func (s *RetentionService) removeTasks(ids []int64) error {
+	if err := s.logs.Delete(context.Background(), ids...); err != nil {
+		return err
+	}
+	if err := s.artifacts.Delete(context.Background(), ids...); err != nil {
+		return err
+	}
+	return s.taskRepo.HardDelete(ids)
+}
+
+// expiredTasks returns the IDs of tasks (newest first) that fall outside the
+// policy. A run is expired when it is beyond the KeepLastN most recent runs or
+// older than KeepDays. When KeepFailedDays is set, failed and timed out runs
+// are judged only by that age instead.
+func expiredTasks(policy *model.RetentionPolicy, tasks []model.Task, now time.Time) []int64 {
+	var expired []int64
+	kept := 0
+	for _, task := range tasks {
+		if isFailed(task.Status) && policy.KeepFailedDays > 0 {
+			if now.Sub(task.CreatedAt) > days(policy.KeepFailedDays) {
+				expired = append(expired, task.ID)
+			}
+			continue
+		}
+
+		kept++
+		if policy.KeepLastN > 0 && kept > policy.KeepLastN {
+			expired = append(expired, task.ID)
+			continue
+		}
+		if policy.KeepDays > 0 && now.Sub(task.CreatedAt) > days(policy.KeepDays) {
+			expired = append(expired, task.ID)
+		}
+	}
+	return expired
+}
+
+// isFailed reports whether a task with the status ended in failure.
+func isFailed(status string) bool {
+	return status == "failed" || status == "timed_out"
+}
+
+func days(n int) time.Duration {
+	return time.Duration(n) * 24 * time.Hour
+}
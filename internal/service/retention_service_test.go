@@ -0,0 +1,27 @@
+package service
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"gogo-scheduler/internal/model"
+)
+
+func TestExpiredTasksKeepsFailuresLonger(t *testing.T) {
+	now := time.Now()
+	age := func(d int) time.Time { return now.Add(-time.Duration(d) * 24 * time.Hour) }
+	tasks := []model.Task{ // newest first
+		{ID: 5, Status: "success", CreatedAt: age(1)},
+		{ID: 4, Status: "timed_out", CreatedAt: age(2)},
+		{ID: 3, Status: "failed", CreatedAt: age(3)},
+		{ID: 2, Status: "success", CreatedAt: age(4)},
+		{ID: 1, Status: "timed_out", CreatedAt: age(40)},
+	}
+	policy := &model.RetentionPolicy{KeepLastN: 1, KeepDays: 7, KeepFailedDays: 30}
+
+	expired := expiredTasks(policy, tasks, now)
+	if !slices.Equal(expired, []int64{2, 1}) {
+		t.Errorf("expired = %v, want [2 1]", expired)
+	}
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/tracing"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrScriptNotFound = errors.New("script not found")
+	ErrTaskNotFound   = errors.New("task not found")
+	ErrAccessDenied   = errors.New("access denied")
+	// ErrRunQuotaExceeded is returned when a project already has as many
+	// pending and running tasks as it allows.
+	ErrRunQuotaExceeded = errors.New("project has reached its limit of concurrent runs")
+)
+
+// owns reports whether the user has full control over the script: its
+// owner or an administrator.
+func owns(user *model.User, script *model.Script) bool {
+	return user.Role == model.RoleAdmin || script.OwnerID == user.ID
+}
+
+// accessRank returns the rank of the user's share level on the script, or
+// -1 if the user cannot read it.
+func (s *ScriptService) accessRank(ctx context.Context, user *model.User, script *model.Script) (int, error) {
+	if owns(user, script) {
+		return model.ShareLevelRank(model.PermissionEdit), nil
+	}
+
+	reader, err := s.reader(ctx, user)
+	if err != nil {
+		return -1, err
+	}
+	shares, err := s.shareRepo.WithContext(ctx).ListFor(script.ID, *reader)
+	if err != nil {
+		return -1, err
+	}
+	rank := -1
+	for _, share := range shares {
+		rank = max(rank, model.ShareLevelRank(share.Level))
+	}
+	return rank, nil
+}
+
+// authorize checks that the user has at least the given level on the
+// script. Scripts the user cannot read are reported as not found.
+func (s *ScriptService) authorize(ctx context.Context, user *model.User, script *model.Script, level model.Permission) error {
+	rank, err := s.accessRank(ctx, user, script)
+	if err != nil {
+		return err
+	}
+	if rank < 0 {
+		return ErrScriptNotFound
+	}
+	if rank < model.ShareLevelRank(level) {
+		return ErrAccessDenied
+	}
+	return nil
+}
+
+// script returns the script if it belongs to the scope's project and the
+// user has at least the given level on it.
+func (s *ScriptService) script(ctx context.Context, scope model.ProjectScope, id int64, level model.Permission) (*model.Script, error) {
+	script, err := s.repo.WithContext(ctx).GetByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) || err == nil && script.ProjectID != scope.ProjectID {
+		return nil, ErrScriptNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(ctx, scope.User, script, level); err != nil {
+		return nil, err
+	}
+	return script, nil
+}
+
+// ownedScript returns the script if the user owns it.
+func (s *ScriptService) ownedScript(ctx context.Context, scope model.ProjectScope, id int64) (*model.Script, error) {
+	script, err := s.script(ctx, scope, id, model.PermissionRead)
+	if err != nil {
+		return nil, err
+	}
+	if !owns(scope.User, script) {
+		return nil, ErrAccessDenied
+	}
+	return script, nil
+}
+
+// task returns the task if the user has at least the given level on its
+// script, which may have been deleted since.
+func (s *ScriptService) task(ctx context.Context, scope model.ProjectScope, id int64, level model.Permission) (*model.Task, error) {
+	task, err := s.taskRepo.WithContext(ctx).GetByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) || err == nil && task.ProjectID != scope.ProjectID {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	script, err := s.repo.WithContext(ctx).GetByIDWithDeleted(task.ScriptID)
+	if err != nil {
+		return nil, err
+	}
+	err = s.authorize(ctx, scope.User, script, level)
+	if errors.Is(err, ErrScriptNotFound) {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// reader returns the filter for the scripts the user can read, or nil for
+// administrators, who can read all of them.
+func (s *ScriptService) reader(ctx context.Context, user *model.User) (*model.ScriptReader, error) {
+	if user.Role == model.RoleAdmin {
+		return nil, nil
+	}
+	groupIDs, err := s.groupRepo.WithContext(ctx).GroupIDsOf(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ScriptReader{UserID: user.ID, GroupIDs: groupIDs}, nil
+}
+
+// ListShares returns who a script is shared with. Only its owner can see
+// this.
+func (s *ScriptService) ListShares(ctx context.Context, scope model.ProjectScope, scriptID int64) ([]model.ScriptShare, error) {
+	ctx, span := tracing.Start(ctx, "ScriptService.ListShares")
+	defer span.End()
+
+	if _, err := s.ownedScript(ctx, scope, scriptID); err != nil {
+		return nil, err
+	}
+	return s.shareRepo.WithContext(ctx).ListByScript(scriptID)
+}
+
+// ShareScript grants a user or group access to a script, replacing an
+// earlier grant to the same user or group.
+func (s *ScriptService) ShareScript(ctx context.Context, scope model.ProjectScope, scriptID int64, req model.ShareRequest) (*model.ScriptShare, error) {
+	ctx, span := tracing.Start(ctx, "ScriptService.ShareScript")
+	defer span.End()
+
+	if _, err := s.ownedScript(ctx, scope, scriptID); err != nil {
+		return nil, err
+	}
+	if (req.UserID == nil) == (req.GroupID == nil) {
+		return nil, errors.New("share with either user_id or group_id")
+	}
+	if model.ShareLevelRank(req.Level) < 0 {
+		return nil, errors.New("level must be read, run or edit")
+	}
+	if req.UserID != nil {
+		if _, err := s.userRepo.WithContext(ctx).FindByID(uint(*req.UserID)); err != nil {
+			return nil, errors.New("user not found")
+		}
+	} else if _, err := s.groupRepo.WithContext(ctx).GetByID(*req.GroupID); err != nil {
+		return nil, errors.New("group not found")
+	}
+
+	share := &model.ScriptShare{ScriptID: scriptID, UserID: req.UserID, GroupID: req.GroupID, Level: req.Level}
+	if err := s.shareRepo.WithContext(ctx).Save(share); err != nil {
+		return nil, err
+	}
+	return share, nil
+}
+
+func (s *ScriptService) UnshareScript(ctx context.Context, scope model.ProjectScope, scriptID, shareID int64) error {
+	ctx, span := tracing.Start(ctx, "ScriptService.UnshareScript")
+	defer span.End()
+
+	if _, err := s.ownedScript(ctx, scope, scriptID); err != nil {
+		return err
+	}
+	found, err := s.shareRepo.WithContext(ctx).Delete(scriptID, shareID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("share not found")
+	}
+	return nil
+}
+
+// TransferOwnership makes another user the owner of a script.
+func (s *ScriptService) TransferOwnership(ctx context.Context, scope model.ProjectScope, scriptID, ownerID int64) (*model.Script, error) {
+	ctx, span := tracing.Start(ctx, "ScriptService.TransferOwnership")
+	defer span.End()
+
+	script, err := s.ownedScript(ctx, scope, scriptID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.userRepo.WithContext(ctx).FindByID(uint(ownerID)); err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	script.OwnerID = ownerID
+	if err := s.repo.WithContext(ctx).Update(script); err != nil {
+		return nil, err
+	}
+	return script, nil
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"context"
+
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/tracing"
+)
+
+// ListNotifications returns the channels the script sends task events to.
+func (s *ScriptService) ListNotifications(ctx context.Context, scope model.ProjectScope, scriptID int64) ([]model.ScriptNotification, error) {
+	ctx, span := tracing.Start(ctx, "ScriptService.ListNotifications")
+	defer span.End()
+
+	if _, err := s.script(ctx, scope, scriptID, model.PermissionRead); err != nil {
+		return nil, err
+	}
+	return s.notifier.ListSubscriptions(scriptID)
+}
+
+// SetNotifications replaces the channels the script sends task events to.
+func (s *ScriptService) SetNotifications(ctx context.Context, scope model.ProjectScope, scriptID int64, reqs []model.ScriptNotificationRequest) ([]model.ScriptNotification, error) {
+	ctx, span := tracing.Start(ctx, "ScriptService.SetNotifications")
+	defer span.End()
+
+	script, err := s.script(ctx, scope, scriptID, model.PermissionEdit)
+	if err != nil {
+		return nil, err
+	}
+	return s.notifier.SetSubscriptions(script, reqs)
+}
@@ -1,41 +1,120 @@
 package service
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"gogo-scheduler/internal/logging"
+	"gogo-scheduler/internal/metrics"
 	"gogo-scheduler/internal/model"
 	"gogo-scheduler/internal/repository"
-	"log"
+	"gogo-scheduler/internal/tracing"
+	"io"
+	"log/slog"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
+	"slices"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/panjf2000/ants/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// ScriptService manages scripts and their tasks. Every method that takes a
+// project scope checks that the script belongs to the project and that the
+// user may access it.
 type ScriptService struct {
-	repo     *repository.ScriptRepository
-	taskRepo *repository.TaskRepository
+	repo        *repository.ScriptRepository
+	taskRepo    *repository.TaskRepository
+	shareRepo   *repository.ShareRepository
+	groupRepo   *repository.GroupRepository
+	userRepo    *repository.UserRepository
+	projectRepo *repository.ProjectRepository
+	logs        *LogService
+	artifacts   *ArtifactService
+	notifier    *NotificationService
+	pool        *ants.Pool // runs tasks
+	executor    ExecutorConfig
+	runMu       sync.Mutex // serializes quota checks with starting tasks
+	expiryLoop  heartbeat  // runs of StartApprovalExpiry
 }
 
-func NewScriptService(repo *repository.ScriptRepository, taskRepo *repository.TaskRepository) *ScriptService {
-	return &ScriptService{repo: repo, taskRepo: taskRepo}
+// ExecutorConfig controls how tasks are run.
+type ExecutorConfig struct {
+	WorkspaceDir string        // each task runs in its own directory below this one
+	ApprovalTTL  time.Duration // how long runs wait for approval
+	Python       string        // python3, or python on Windows, if empty
+	Shell        string        // bash if empty
 }
 
-func (s *ScriptService) CreateScript(name, scriptType, content string) (*model.Script, error) {
+func NewScriptService(repo *repository.ScriptRepository, taskRepo *repository.TaskRepository, shareRepo *repository.ShareRepository, groupRepo *repository.GroupRepository,
+	userRepo *repository.UserRepository, projectRepo *repository.ProjectRepository, logs *LogService, artifacts *ArtifactService, notifier *NotificationService, pool *ants.Pool, executor ExecutorConfig) *ScriptService {
+	if executor.Python == "" {
+		executor.Python = "python3"
+		// if windows, use pythonw
+		if runtime.GOOS == "windows" {
+			executor.Python = "python"
+		}
+	}
+	if executor.Shell == "" {
+		executor.Shell = "bash"
+	}
+	return &ScriptService{
+		repo:        repo,
+		taskRepo:    taskRepo,
+		shareRepo:   shareRepo,
+		groupRepo:   groupRepo,
+		userRepo:    userRepo,
+		projectRepo: projectRepo,
+		logs:        logs,
+		artifacts:   artifacts,
+		notifier:    notifier,
+		pool:        pool,
+		executor:    executor,
+	}
+}
+
+// CreateScript creates a script in the scope's project, owned by the user.
+func (s *ScriptService) CreateScript(ctx context.Context, scope model.ProjectScope, req model.ScriptRequest) (*model.Script, error) {
+	ctx, span := tracing.Start(ctx, "ScriptService.CreateScript")
+	defer span.End()
+
+	if err := s.validateApprovers(ctx, req.Approvers); err != nil {
+		return nil, err
+	}
 	script := &model.Script{
-		Name:    name,
-		Type:    scriptType,
-		Content: content,
+		ProjectID:   scope.ProjectID,
+		OwnerID:     scope.User.ID,
+		Name:        req.Name,
+		Type:        req.Type,
+		Content:     req.Content,
+		Description: req.Description,
+		Tags:        req.Tags,
+		Artifacts:   req.Artifacts,
+
+		RequiresApproval: req.RequiresApproval,
+		Approvers:        req.Approvers,
+		Timeout:          req.Timeout,
 	}
-	err := s.repo.Create(script)
+	err := s.repo.WithContext(ctx).Create(script)
 	return script, err
 }
 
-func (s *ScriptService) RunScriptAsync(scriptID int64) (int64, error) {
-	script, err := s.repo.GetByID(scriptID)
+// RunScriptAsync starts a run of the script and returns its task. Runs of
+// scripts that require approval are held in the awaiting_approval state
+// instead.
+func (s *ScriptService) RunScriptAsync(ctx context.Context, scope model.ProjectScope, scriptID int64) (*model.Task, error) {
+	ctx, span := tracing.Start(ctx, "ScriptService.RunScriptAsync")
+	defer span.End()
+
+	script, err := s.script(ctx, scope, scriptID, model.PermissionRun)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	// taskName format: scriptType_scriptID_scriptName_timestamp
@@ -43,118 +122,387 @@ func (s *ScriptService) RunScriptAsync(scriptID int64) (int64, error) {
 
 	// Create task record
 	task := &model.Task{
-		Name:       taskName,
-		ScriptID:   script.ID,
-		ScriptName: script.Name,
-		Status:     "running",
-		LastRun:    time.Now(),
+		Name:        taskName,
+		ScriptID:    script.ID,
+		ProjectID:   script.ProjectID,
+		ScriptName:  script.Name,
+		Status:      "running",
+		LastRun:     time.Now(),
+		RequestedBy: scope.User.ID,
+	}
+	if script.RequiresApproval {
+		expiresAt := time.Now().Add(s.executor.ApprovalTTL)
+		task.Status = "awaiting_approval"
+		task.ApprovalExpiresAt = &expiresAt
+		// approvers decide on this content, whatever the script holds later
+		task.ScriptType = script.Type
+		task.ScriptContent = script.Content
+		if err := s.taskRepo.WithContext(ctx).Create(task); err != nil {
+			return nil, err
+		}
+		return task, nil
 	}
-	if err := s.taskRepo.Create(task); err != nil {
-		return 0, err
+
+	err = s.admit(ctx, task.ProjectID, func() error {
+		return s.taskRepo.WithContext(ctx).Create(task)
+	})
+	if err != nil {
+		return nil, err
 	}
+	return task, s.submit(ctx, task)
+}
+
+// admit calls start, which must record a task as running, unless the
+// project has reached its limit of concurrent runs.
+func (s *ScriptService) admit(ctx context.Context, projectID int64, start func() error) error {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
 
-	err = ants.Submit(func() {
-		_, err := s.RunScript(scriptID, task.ID)
+	project, err := s.projectRepo.WithContext(ctx).GetByID(projectID)
+	if err != nil {
+		return err
+	}
+	if project.MaxConcurrentRuns > 0 {
+		active, err := s.taskRepo.WithContext(ctx).CountActive(project.ID)
 		if err != nil {
-			log.Println("error running script:", err)
+			return err
+		}
+		if active >= int64(project.MaxConcurrentRuns) {
+			return ErrRunQuotaExceeded
 		}
+	}
+	return start()
+}
+
+// submit runs a task in the worker pool. The run is traced and logged as
+// part of ctx but not cancelled with it.
+func (s *ScriptService) submit(ctx context.Context, task *model.Task) error {
+	ctx = logging.With(context.WithoutCancel(ctx), "task_id", task.ID, "script_id", task.ScriptID)
+	_, queueSpan := tracing.Start(ctx, "task.queue", trace.WithAttributes(attribute.Int64("task.id", task.ID)))
+	queued := time.Now()
+	metrics.TasksPending.Inc()
+	err := s.pool.Submit(func() {
+		queueSpan.End()
+		metrics.TasksPending.Dec()
+		metrics.TaskQueueWait.Observe(time.Since(queued).Seconds())
+		s.RunScript(ctx, task.ScriptID, task.ID)
 	})
-	return task.ID, err
+	if err != nil {
+		metrics.TasksPending.Dec()
+		tracing.End(queueSpan, err)
+		s.failTask(ctx, task, err)
+	}
+	return err
+}
 
+// failTask marks a task that could not be run as failed, so that it no
+// longer counts against the project's limit of concurrent runs.
+func (s *ScriptService) failTask(ctx context.Context, task *model.Task, cause error) {
+	endTime := time.Now()
+	task.Status = "failed"
+	task.Error = cause.Error()
+	task.EndTime = &endTime
+	if err := s.taskRepo.WithContext(ctx).Update(task); err != nil {
+		slog.ErrorContext(ctx, "recording task failure failed", "error", err)
+	}
 }
 
-func (s *ScriptService) RunScript(scriptID, taskID int64) (string, error) {
-	script, err := s.repo.GetByID(scriptID)
+// RunScript runs a task and returns its output. The script gets the trace
+// context of the run in the TRACEPARENT environment variable. Errors that
+// keep the script from starting are logged and fail the task; the outcome
+// of a run is logged when it finishes.
+func (s *ScriptService) RunScript(ctx context.Context, scriptID, taskID int64) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "ScriptService.RunScript", trace.WithAttributes(
+		attribute.Int64("task.id", taskID),
+		attribute.Int64("script.id", scriptID),
+	))
+	var task *model.Task
+	started := false
+	defer func() {
+		if err != nil && !started {
+			slog.ErrorContext(ctx, "running task failed", "error", err)
+			if task != nil {
+				s.failTask(ctx, task, err)
+			}
+		}
+		tracing.End(span, err)
+	}()
+
+	task, err = s.taskRepo.WithContext(ctx).GetByID(taskID)
 	if err != nil {
+		task = nil
 		return "", err
 	}
 
-	task, err := s.taskRepo.GetByID(taskID)
+	script, err := s.repo.WithContext(ctx).GetByID(scriptID)
 	if err != nil {
 		return "", err
 	}
+	span.SetAttributes(attribute.String("script.name", script.Name), attribute.String("script.type", script.Type))
+	if task.ScriptContent != "" {
+		script.Type, script.Content = task.ScriptType, task.ScriptContent
+	}
+	metrics.TasksRunning.Inc()
+	defer metrics.TasksRunning.Dec()
+
+	runCtx := context.Background()
+	if script.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(runCtx, time.Duration(script.Timeout)*time.Second)
+		defer cancel()
+	}
 
 	var cmd *exec.Cmd
-	var output bytes.Buffer
+	output := s.logs.NewCapture()
 
 	switch script.Type {
 	case "python":
-		// if windows, use pythonw
-		if runtime.GOOS == "windows" {
-			cmd = exec.Command("python", "-c", script.Content)
-		} else {
-			cmd = exec.Command("python3", "-c", script.Content)
-		}
+		cmd = exec.CommandContext(runCtx, s.executor.Python, "-c", script.Content)
 
 	case "shell":
-		cmd = exec.Command("bash", "-c", script.Content)
+		cmd = exec.CommandContext(runCtx, s.executor.Shell, "-c", script.Content)
 
 	default:
 		return "", fmt.Errorf("unsupported script type: %s", script.Type)
 	}
 
-	cmd.Stdout = &output
-	cmd.Stderr = &output
+	workspace := filepath.Join(s.executor.WorkspaceDir, strconv.FormatInt(task.ID, 10))
+	if err := os.MkdirAll(workspace, 0755); err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(workspace)
+
+	cmd.Dir = workspace
+	cmd.Stdout = output.StdoutWriter()
+	cmd.Stderr = output.StderrWriter()
+	// don't wait forever for children of a killed process that still hold
+	// the output pipes
+	cmd.WaitDelay = 5 * time.Second
+
+	startTime := time.Now()
+	task.StartTime = &startTime
+	s.taskRepo.WithContext(ctx).Update(task)
+	started = true
+	slog.InfoContext(ctx, "task started", "script", script.Name, "type", script.Type)
+	s.notify(ctx, model.EventTaskStarted, task, script)
 
+	execCtx, execSpan := tracing.Start(ctx, "task.execute")
+	if env := tracing.Environ(execCtx); len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
 	err = cmd.Run()
+	tracing.End(execSpan, err)
 	endTime := time.Now()
 	task.EndTime = &endTime
-	task.Output = output.String()
 
+	saveCtx, saveSpan := tracing.Start(ctx, "task.save_logs")
+	saveErr := s.logs.Save(saveCtx, task, output)
+	tracing.End(saveSpan, saveErr)
+	if saveErr != nil {
+		slog.ErrorContext(ctx, "saving task log failed", "error", saveErr)
+	}
+	collectCtx, collectSpan := tracing.Start(ctx, "task.collect_artifacts")
+	collectErr := s.artifacts.Collect(collectCtx, task, workspace, script.Artifacts)
+	tracing.End(collectSpan, collectErr)
+	if collectErr != nil {
+		slog.ErrorContext(ctx, "collecting artifacts failed", "error", collectErr)
+	}
+
+	if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		task.Status = "timed_out"
+		task.Error = fmt.Sprintf("timed out after %ds", script.Timeout)
+		s.taskRepo.WithContext(ctx).Update(task)
+		observeRun(ctx, script, task)
+		s.notify(ctx, model.EventTaskTimedOut, task, script)
+		return output.Stdout.String(), errors.New(task.Error)
+	}
 	if err != nil {
 		task.Status = "failed"
-		s.taskRepo.Update(task)
-		return output.String(), err
+		task.Error = err.Error()
+		s.taskRepo.WithContext(ctx).Update(task)
+		observeRun(ctx, script, task)
+		s.notify(ctx, model.EventTaskFailed, task, script)
+		return output.Stdout.String(), err
 	}
 
 	task.Status = "success"
-	s.taskRepo.Update(task)
-	return output.String(), nil
+	s.taskRepo.WithContext(ctx).Update(task)
+	observeRun(ctx, script, task)
+	s.notify(ctx, model.EventTaskSucceeded, task, script)
+	return output.Stdout.String(), nil
 }
 
-func (s *ScriptService) GetScript(id int64) (*model.Script, error) {
-	return s.repo.GetByID(id)
+// observeRun records a finished run in the metrics and the log.
+func observeRun(ctx context.Context, script *model.Script, task *model.Task) {
+	duration := task.EndTime.Sub(*task.StartTime)
+	metrics.TaskRuns.WithLabelValues(script.Name, task.Status).Inc()
+	metrics.TaskDuration.WithLabelValues(script.Name, task.Status).Observe(duration.Seconds())
+
+	args := []any{"script", script.Name, "status", task.Status, "duration_ms", duration.Milliseconds()}
+	if task.Error != "" {
+		args = append(args, "error", task.Error)
+	}
+	slog.InfoContext(ctx, "task finished", args...)
 }
 
-func (s *ScriptService) ListScripts() ([]model.Script, error) {
-	return s.repo.List()
+func (s *ScriptService) notify(ctx context.Context, event string, task *model.Task, script *model.Script) {
+	if s.notifier != nil {
+		s.notifier.Notify(ctx, event, task, script)
+	}
 }
 
-func (s *ScriptService) DeleteScript(id int64) error {
-	return s.repo.Delete(id)
+func (s *ScriptService) GetScript(ctx context.Context, scope model.ProjectScope, id int64) (*model.Script, error) {
+	ctx, span := tracing.Start(ctx, "ScriptService.GetScript")
+	defer span.End()
+
+	return s.script(ctx, scope, id, model.PermissionRead)
 }
 
-func (s *ScriptService) ListTasks(scriptID *int64) ([]model.Task, error) {
-	return s.taskRepo.List(scriptID)
+// ListScripts returns the scripts of the scope's project that the user can
+// read.
+func (s *ScriptService) ListScripts(ctx context.Context, scope model.ProjectScope, q model.ScriptQuery) (*model.PageResult[model.Script], error) {
+	ctx, span := tracing.Start(ctx, "ScriptService.ListScripts")
+	defer span.End()
+
+	reader, err := s.reader(ctx, scope.User)
+	if err != nil {
+		return nil, err
+	}
+	q.ProjectID = scope.ProjectID
+	q.Reader = reader
+
+	scripts, total, err := s.repo.WithContext(ctx).List(q)
+	if err != nil {
+		return nil, err
+	}
+	return &model.PageResult[model.Script]{
+		Items:    scripts,
+		Total:    total,
+		Page:     q.Page,
+		PageSize: q.PageSize,
+	}, nil
 }
 
-func (s *ScriptService) GetTask(id int64) (*model.Task, error) {
-	return s.taskRepo.GetByID(id)
+// DeleteScript deletes a script. Only its owner can do this.
+func (s *ScriptService) DeleteScript(ctx context.Context, scope model.ProjectScope, id int64) error {
+	ctx, span := tracing.Start(ctx, "ScriptService.DeleteScript")
+	defer span.End()
+
+	if _, err := s.ownedScript(ctx, scope, id); err != nil {
+		return err
+	}
+	return s.repo.WithContext(ctx).Delete(id)
+}
+
+// ListTasks returns a page of the tasks of the scope's project whose
+// scripts the user can read, without the content of their scripts.
+func (s *ScriptService) ListTasks(ctx context.Context, scope model.ProjectScope, scriptID *int64, page, pageSize int) (*model.PageResult[model.Task], error) {
+	ctx, span := tracing.Start(ctx, "ScriptService.ListTasks")
+	defer span.End()
+
+	reader, err := s.reader(ctx, scope.User)
+	if err != nil {
+		return nil, err
+	}
+	page, pageSize = model.NormalizePage(page, pageSize)
+	tasks, total, err := s.taskRepo.WithContext(ctx).List(scope.ProjectID, scriptID, reader, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &model.PageResult[model.Task]{Items: tasks, Total: total, Page: page, PageSize: pageSize}, nil
 }
 
-func (s *ScriptService) DeleteTask(id int64) error {
-	return s.taskRepo.Delete(id)
+func (s *ScriptService) GetTask(ctx context.Context, scope model.ProjectScope, id int64) (*model.Task, error) {
+	ctx, span := tracing.Start(ctx, "ScriptService.GetTask")
+	defer span.End()
+
+	return s.task(ctx, scope, id, model.PermissionRead)
 }
 
-func (s *ScriptService) UpdateScript(id int64, name, scriptType, content string) (*model.Script, error) {
-	script, err := s.repo.GetByID(id)
+func (s *ScriptService) ListArtifacts(ctx context.Context, scope model.ProjectScope, taskID int64) ([]model.Artifact, error) {
+	ctx, span := tracing.Start(ctx, "ScriptService.ListArtifacts")
+	defer span.End()
+
+	if _, err := s.task(ctx, scope, taskID, model.PermissionRead); err != nil {
+		return nil, err
+	}
+	return s.artifacts.List(taskID)
+}
+
+// OpenArtifact returns an artifact of a task and a reader for its content.
+func (s *ScriptService) OpenArtifact(ctx context.Context, scope model.ProjectScope, taskID, id int64) (*model.Artifact, io.ReadCloser, error) {
+	ctx, span := tracing.Start(ctx, "ScriptService.OpenArtifact")
+	defer span.End()
+
+	if _, err := s.task(ctx, scope, taskID, model.PermissionRead); err != nil {
+		return nil, nil, err
+	}
+	return s.artifacts.Open(ctx, taskID, id)
+}
+
+// GetTaskLog returns a byte range of one of a task's log streams and the
+// total size of that stream.
+func (s *ScriptService) GetTaskLog(ctx context.Context, scope model.ProjectScope, id int64, stream string, offset, limit int64) ([]byte, int64, error) {
+	ctx, span := tracing.Start(ctx, "ScriptService.GetTaskLog")
+	defer span.End()
+
+	task, err := s.task(ctx, scope, id, model.PermissionRead)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.logs.Read(ctx, task, stream, offset, limit)
+}
+
+func (s *ScriptService) DeleteTask(ctx context.Context, scope model.ProjectScope, id int64) error {
+	ctx, span := tracing.Start(ctx, "ScriptService.DeleteTask")
+	defer span.End()
+
+	if _, err := s.task(ctx, scope, id, model.PermissionEdit); err != nil {
+		return err
+	}
+	return s.taskRepo.WithContext(ctx).Delete(id)
+}
+
+func (s *ScriptService) UpdateScript(ctx context.Context, scope model.ProjectScope, id int64, req model.ScriptRequest) (*model.Script, error) {
+	ctx, span := tracing.Start(ctx, "ScriptService.UpdateScript")
+	defer span.End()
+
+	if err := s.validateApprovers(ctx, req.Approvers); err != nil {
+		return nil, err
+	}
+	script, err := s.script(ctx, scope, id, model.PermissionEdit)
 	if err != nil {
 		return nil, err
 	}
+	// the approval gate protects the script from its editors, so only
+	// those with full control may change it
+	approvalChanged := req.RequiresApproval != script.RequiresApproval || !slices.Equal(req.Approvers, script.Approvers)
+	if approvalChanged && !owns(scope.User, script) {
+		return nil, ErrAccessDenied
+	}
 
-	script.Name = name
-	script.Type = scriptType
-	script.Content = content
+	script.Name = req.Name
+	script.Type = req.Type
+	script.Content = req.Content
+	script.Description = req.Description
+	script.Tags = req.Tags
+	script.Artifacts = req.Artifacts
+	script.RequiresApproval = req.RequiresApproval
+	script.Approvers = req.Approvers
+	script.Timeout = req.Timeout
 
-	err = s.repo.Update(script)
+	err = s.repo.WithContext(ctx).Update(script)
 	return script, err
 }
 
-func (s *ScriptService) RerunTask(taskID int64) (int64, error) {
-	task, err := s.taskRepo.GetByID(taskID)
+func (s *ScriptService) RerunTask(ctx context.Context, scope model.ProjectScope, taskID int64) (*model.Task, error) {
+	ctx, span := tracing.Start(ctx, "ScriptService.RerunTask")
+	defer span.End()
+
+	task, err := s.task(ctx, scope, taskID, model.PermissionRead)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	return s.RunScriptAsync(task.ScriptID)
+	return s.RunScriptAsync(ctx, scope, task.ScriptID)
 }
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/repository"
+)
+
+func TestUpdateScriptApprovalNeedsOwner(t *testing.T) {
+	db := newTestDB(t)
+	userRepo := repository.NewUserRepository(db)
+	shareRepo := repository.NewShareRepository(db)
+	s := &ScriptService{
+		repo:      repository.NewScriptRepository(db),
+		shareRepo: shareRepo,
+		groupRepo: repository.NewGroupRepository(db),
+		userRepo:  userRepo,
+	}
+	ctx := context.Background()
+
+	owner := &model.User{Username: "owner", Role: model.RoleEditor}
+	editor := &model.User{Username: "editor", Role: model.RoleEditor}
+	for _, user := range []*model.User{owner, editor} {
+		if err := userRepo.Create(user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := model.ScriptRequest{Name: "deploy", Type: "shell", Content: "echo deploy", RequiresApproval: true}
+	script, err := s.CreateScript(ctx, model.ProjectScope{User: owner}, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := shareRepo.Save(&model.ScriptShare{ScriptID: script.ID, UserID: &editor.ID, Level: model.PermissionEdit}); err != nil {
+		t.Fatal(err)
+	}
+	editorScope := model.ProjectScope{User: editor}
+
+	// editing the content keeps the approval gate
+	req.Content = "echo deploy --force"
+	if _, err := s.UpdateScript(ctx, editorScope, script.ID, req); err != nil {
+		t.Fatalf("editor updating the content: %v", err)
+	}
+
+	for name, change := range map[string]model.ScriptRequest{
+		"turn approval off": {Name: req.Name, Type: req.Type, Content: req.Content},
+		"choose approvers":  {Name: req.Name, Type: req.Type, Content: req.Content, RequiresApproval: true, Approvers: []int64{editor.ID}},
+	} {
+		if _, err := s.UpdateScript(ctx, editorScope, script.ID, change); !errors.Is(err, ErrAccessDenied) {
+			t.Errorf("editor trying to %s: err = %v, want ErrAccessDenied", name, err)
+		}
+	}
+	stored, err := s.repo.GetByID(script.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.RequiresApproval || len(stored.Approvers) != 0 {
+		t.Errorf("approval settings changed by an editor: %v, %v", stored.RequiresApproval, stored.Approvers)
+	}
+
+	req.RequiresApproval = false
+	if updated, err := s.UpdateScript(ctx, model.ProjectScope{User: owner}, script.ID, req); err != nil || updated.RequiresApproval {
+		t.Errorf("owner turning approval off: %+v, %v", updated, err)
+	}
+}
+
+func TestListTasksPagesWithoutContent(t *testing.T) {
+	db := newTestDB(t)
+	userRepo := repository.NewUserRepository(db)
+	s := &ScriptService{
+		repo:      repository.NewScriptRepository(db),
+		taskRepo:  repository.NewTaskRepository(db),
+		groupRepo: repository.NewGroupRepository(db),
+		userRepo:  userRepo,
+	}
+	ctx := context.Background()
+
+	user := &model.User{Username: "root", Role: model.RoleAdmin}
+	if err := userRepo.Create(user); err != nil {
+		t.Fatal(err)
+	}
+	scope := model.ProjectScope{User: user}
+	script, err := s.CreateScript(ctx, scope, model.ScriptRequest{Name: "big", Type: "shell", Content: "echo a lot"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		task := &model.Task{ScriptID: script.ID, Status: "awaiting_approval", ScriptType: "shell", ScriptContent: "echo a lot"}
+		if err := s.taskRepo.Create(task); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page, err := s.ListTasks(ctx, scope, nil, 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 3 || len(page.Items) != 1 || page.Page != 2 {
+		t.Fatalf("page = total %d, %d items, page %d; want total 3, 1 item, page 2", page.Total, len(page.Items), page.Page)
+	}
+	task := page.Items[0]
+	if task.Script.Name != "big" || task.Script.Content != "" || task.ScriptContent != "" || task.ScriptType != "shell" {
+		t.Errorf("listed task carries script content: %+v", task)
+	}
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/oidc"
+	"gogo-scheduler/internal/repository"
+)
+
+// SSOLoginTimeout is how long a user has to complete the login at the
+// identity provider.
+const SSOLoginTimeout = 10 * time.Minute
+
+// maxPendingLogins bounds the logins started and not yet completed, which
+// are kept in memory and can be started without authentication.
+const maxPendingLogins = 10000
+
+// ErrTooManyLogins is returned by Begin when too many logins are pending.
+var ErrTooManyLogins = errors.New("too many logins in progress, please try again later")
+
+type SSOConfig struct {
+	OIDC oidc.Config
+	// GroupsClaim names the ID token claim that lists the user's groups.
+	GroupsClaim string
+	// RoleMapping maps IdP groups to roles. A user gets the most privileged
+	// role of their groups, or DefaultRole if none match. With a mapping the
+	// role is synced on every login.
+	RoleMapping map[string]model.Role
+	DefaultRole model.Role
+	// AutoProvision creates users on their first login.
+	AutoProvision bool
+}
+
+type pendingLogin struct {
+	nonce    string
+	verifier string
+	expires  time.Time
+}
+
+// SSOService signs users in through an OpenID Connect provider.
+type SSOService struct {
+	provider    *oidc.Provider
+	cfg         SSOConfig
+	userRepo    *repository.UserRepository
+	authService *AuthService
+
+	mu         sync.Mutex
+	pending    map[string]pendingLogin // by state
+	maxPending int
+}
+
+func NewSSOService(cfg SSOConfig, userRepo *repository.UserRepository, authService *AuthService) *SSOService {
+	if cfg.DefaultRole == "" {
+		cfg.DefaultRole = model.RoleViewer
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	return &SSOService{
+		provider:    oidc.NewProvider(cfg.OIDC),
+		cfg:         cfg,
+		userRepo:    userRepo,
+		authService: authService,
+		pending:     make(map[string]pendingLogin),
+		maxPending:  maxPendingLogins,
+	}
+}
+
+// Begin starts a login and returns the identity provider URL to redirect
+// the browser to, and the state the provider passes back to the callback.
+// The caller must tie the state to the browser, so that a callback URL
+// cannot be completed in another one.
+func (s *SSOService) Begin(ctx context.Context) (authURL, state string, err error) {
+	state, err = randomToken(24)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomToken(24)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := randomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err = s.provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for key, login := range s.pending {
+		if now.After(login.expires) {
+			delete(s.pending, key)
+		}
+	}
+	if len(s.pending) >= s.maxPending {
+		return "", "", ErrTooManyLogins
+	}
+	s.pending[state] = pendingLogin{nonce: nonce, verifier: verifier, expires: now.Add(SSOLoginTimeout)}
+	return authURL, state, nil
+}
+
+// SecureCallback reports whether the identity provider redirects back over
+// HTTPS.
+func (s *SSOService) SecureCallback() bool {
+	return strings.HasPrefix(s.cfg.OIDC.RedirectURL, "https://")
+}
+
+// Complete finishes a login with the code the identity provider returned
+// and signs the user in, creating the account on first login if enabled.
+func (s *SSOService) Complete(ctx context.Context, state, code string) (*model.LoginResponse, error) {
+	s.mu.Lock()
+	login, ok := s.pending[state]
+	delete(s.pending, state)
+	s.mu.Unlock()
+	if !ok || time.Now().After(login.expires) {
+		return nil, errors.New("login session expired, please try again")
+	}
+
+	claims, err := s.provider.Exchange(ctx, code, login.verifier, login.nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByExternalID(model.AuthProviderOIDC, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	role, mapped := mapGroupsToRole(claims.StringList(s.cfg.GroupsClaim), s.cfg.RoleMapping, s.cfg.DefaultRole)
+	if user == nil {
+		if !s.cfg.AutoProvision {
+			return nil, errors.New("no account exists for this user")
+		}
+		if user, err = s.provision(claims, role); err != nil {
+			return nil, err
+		}
+	} else if mapped && user.Role != role {
+		if err := s.userRepo.UpdateRole(user.ID, role); err != nil {
+			return nil, err
+		}
+		user.Role = role
+	}
+
+	return s.authService.CreateSession(user)
+}
+
+func (s *SSOService) provision(claims *oidc.Claims, role model.Role) (*model.User, error) {
+	username, err := s.availableUsername(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	// SSO users never sign in with a password; store an unusable random one
+	password, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	user := &model.User{
+		Username:     username,
+		Password:     password,
+		Role:         role,
+		AuthProvider: model.AuthProviderOIDC,
+		ExternalID:   claims.Subject,
+	}
+	if err := user.HashPassword(); err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// availableUsername picks a username from the claims. If it is taken, a
+// suffix derived from the subject is added rather than linking accounts.
+func (s *SSOService) availableUsername(claims *oidc.Claims) (string, error) {
+	username := claims.PreferredUsername
+	if username == "" {
+		username = claims.Email
+	}
+	if username == "" {
+		username = claims.Subject
+	}
+
+	existing, err := s.userRepo.FindByUsername(username)
+	if err != nil || existing == nil {
+		return username, err
+	}
+
+	sum := sha256.Sum256([]byte(claims.Subject))
+	return strings.Join([]string{username, hex.EncodeToString(sum[:4])}, "-"), nil
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/oidc"
+	"gogo-scheduler/internal/oidc/oidctest"
+	"gogo-scheduler/internal/repository"
+)
+
+func newTestSSOService(t *testing.T, cfg SSOConfig) (*oidctest.Server, *SSOService, *repository.UserRepository) {
+	t.Helper()
+	idp := oidctest.NewServer("scheduler", "client-secret")
+	t.Cleanup(idp.Close)
+
+	db := newTestDB(t)
+	userRepo := repository.NewUserRepository(db)
+	cfg.OIDC = oidc.Config{Issuer: idp.URL, ClientID: idp.ClientID, ClientSecret: idp.ClientSecret, RedirectURL: "http://scheduler.test/api/auth/oidc/callback"}
+	return idp, NewSSOService(cfg, userRepo, newTestAuthService(db)), userRepo
+}
+
+// ssoLogin runs a login through the mock provider up to the callback.
+func ssoLogin(t *testing.T, idp *oidctest.Server, sso *SSOService) (*model.LoginResponse, error) {
+	t.Helper()
+	ctx := context.Background()
+	authURL, state, err := sso.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	code, returned, err := idp.Authorize(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if returned != state {
+		t.Fatalf("provider returned state %q, want %q", returned, state)
+	}
+	return sso.Complete(ctx, state, code)
+}
+
+func TestSSOProvisionsAndMapsRoles(t *testing.T) {
+	idp, sso, userRepo := newTestSSOService(t, SSOConfig{
+		AutoProvision: true,
+		RoleMapping:   map[string]model.Role{"ops": model.RoleOperator, "admins": model.RoleAdmin},
+	})
+
+	idp.SetClaims(map[string]interface{}{"sub": "alice-id", "preferred_username": "alice", "groups": []string{"ops"}})
+	resp, err := ssoLogin(t, idp, sso)
+	if err != nil {
+		t.Fatalf("first login: %v", err)
+	}
+	if resp.Token == "" {
+		t.Error("login returned no token")
+	}
+	user, err := userRepo.FindByExternalID(model.AuthProviderOIDC, "alice-id")
+	if err != nil || user == nil {
+		t.Fatalf("provisioned user not found: %v", err)
+	}
+	if user.Username != "alice" || user.Role != model.RoleOperator {
+		t.Errorf("provisioned user = %s with role %s, want alice with role %s", user.Username, user.Role, model.RoleOperator)
+	}
+
+	// the role follows the groups on every login
+	idp.SetClaims(map[string]interface{}{"sub": "alice-id", "preferred_username": "alice", "groups": []string{"admins"}})
+	if _, err := ssoLogin(t, idp, sso); err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+	user, err = userRepo.FindByExternalID(model.AuthProviderOIDC, "alice-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Role != model.RoleAdmin {
+		t.Errorf("role after the groups changed = %s, want %s", user.Role, model.RoleAdmin)
+	}
+
+	// another subject with a taken username gets its own account
+	idp.SetClaims(map[string]interface{}{"sub": "other-id", "preferred_username": "alice"})
+	if _, err := ssoLogin(t, idp, sso); err != nil {
+		t.Fatalf("login of a second alice: %v", err)
+	}
+	other, err := userRepo.FindByExternalID(model.AuthProviderOIDC, "other-id")
+	if err != nil || other == nil {
+		t.Fatalf("second user not found: %v", err)
+	}
+	if other.ID == user.ID || other.Username == "alice" {
+		t.Errorf("second subject was linked to the existing account: %+v", other)
+	}
+	if other.Role != model.RoleViewer {
+		t.Errorf("role without mapped groups = %s, want %s", other.Role, model.RoleViewer)
+	}
+}
+
+func TestSSOWithoutAutoProvision(t *testing.T) {
+	idp, sso, _ := newTestSSOService(t, SSOConfig{})
+
+	if _, err := ssoLogin(t, idp, sso); err == nil {
+		t.Error("login of an unknown user succeeded without auto-provisioning")
+	}
+}
+
+func TestSSOCompleteRejectsUnknownState(t *testing.T) {
+	idp, sso, _ := newTestSSOService(t, SSOConfig{AutoProvision: true})
+	ctx := context.Background()
+
+	authURL, state, err := sso.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _, err := idp.Authorize(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sso.Complete(ctx, "forged-state", code); err == nil {
+		t.Error("Complete with an unknown state succeeded")
+	}
+
+	if _, err := sso.Complete(ctx, state, code); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	// a state can only be used once
+	if _, err := sso.Complete(ctx, state, code); err == nil {
+		t.Error("Complete with a used state succeeded")
+	}
+}
+
+func TestSSOLimitsPendingLogins(t *testing.T) {
+	idp, sso, _ := newTestSSOService(t, SSOConfig{AutoProvision: true})
+	sso.maxPending = 2
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := sso.Begin(ctx); err != nil {
+			t.Fatalf("Begin %d: %v", i, err)
+		}
+	}
+	if _, _, err := sso.Begin(ctx); !errors.Is(err, ErrTooManyLogins) {
+		t.Fatalf("Begin beyond the limit: err = %v, want ErrTooManyLogins", err)
+	}
+
+	// expired logins make room again
+	sso.mu.Lock()
+	for state, login := range sso.pending {
+		login.expires = time.Now().Add(-time.Second)
+		sso.pending[state] = login
+	}
+	sso.mu.Unlock()
+	idp.SetClaims(map[string]interface{}{"sub": "carol-id", "preferred_username": "carol"})
+	if _, err := ssoLogin(t, idp, sso); err != nil {
+		t.Errorf("login after the pending ones expired: %v", err)
+	}
+}
@@ -0,0 +1,251 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/totp"
+)
+
+const (
+	totpIssuer = "Gogo-Scheduler"
+	// twoFactorChallengeTimeout is how long a user has to enter a code after
+	// the password was accepted.
+	twoFactorChallengeTimeout = 5 * time.Minute
+	maxTwoFactorAttempts      = 5
+	recoveryCodeCount         = 10
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type twoFactorChallenge struct {
+	userID   int64
+	username string // as given to Login, for the login throttle
+	attempts int
+	expires  time.Time
+}
+
+// EnrollTOTP generates a new TOTP secret for the user. Two-factor
+// authentication is enabled once ActivateTOTP confirms a code from it.
+func (s *AuthService) EnrollTOTP(user *model.User) (*model.TOTPEnrollResponse, error) {
+	if user.TOTPEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorRepo.SetSecret(user.ID, secret); err != nil {
+		return nil, err
+	}
+	return &model.TOTPEnrollResponse{
+		Secret: secret,
+		URI:    totp.ProvisioningURI(totpIssuer, user.Username, secret),
+	}, nil
+}
+
+// ActivateTOTP enables two-factor authentication after checking a code
+// from the enrolled secret, and returns new recovery codes.
+func (s *AuthService) ActivateTOTP(user *model.User, code string) ([]string, error) {
+	if user.TOTPEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	if user.TOTPSecret == "" {
+		return nil, errors.New("start the enrollment first")
+	}
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return nil, errors.New("invalid code")
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorRepo.Enable(user.ID, step, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP turns off two-factor authentication for the user. It is not
+// possible while an administrator requires it.
+func (s *AuthService) DisableTOTP(user *model.User, code string) error {
+	settings, err := s.SecuritySettings()
+	if err != nil {
+		return err
+	}
+	if settings.RequireTwoFactor {
+		return errors.New("two-factor authentication is required for all users")
+	}
+	if err := s.verifyTwoFactorCode(user, code); err != nil {
+		return err
+	}
+	return s.twoFactorRepo.Disable(user.ID)
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes.
+func (s *AuthService) RegenerateRecoveryCodes(user *model.User, code string) ([]string, error) {
+	if err := s.verifyTwoFactorCode(user, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorRepo.ReplaceRecoveryCodes(user.ID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// CompleteTwoFactor finishes a login that returned a challenge. ip is the
+// client address; wrong codes count as failed logins of the username and
+// the address, like wrong passwords.
+func (s *AuthService) CompleteTwoFactor(challenge, code, ip string) (*model.LoginResponse, error) {
+	s.mu.Lock()
+	c, ok := s.challenges[challenge]
+	s.mu.Unlock()
+	if !ok || time.Now().After(c.expires) {
+		return nil, errors.New("login expired, please sign in again")
+	}
+	if err := s.throttle.Check(c.username, ip); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	c.attempts++
+	if c.attempts >= maxTwoFactorAttempts {
+		delete(s.challenges, challenge)
+	}
+	s.mu.Unlock()
+
+	user, err := s.userRepo.FindByID(uint(c.userID))
+	if err != nil {
+		return nil, err
+	}
+	if user.Disabled {
+		return nil, errors.New("account is disabled")
+	}
+	if err := s.verifyTwoFactorCode(user, code); err != nil {
+		s.throttle.Fail(c.username, ip)
+		return nil, err
+	}
+	s.throttle.Succeed(c.username)
+
+	s.mu.Lock()
+	delete(s.challenges, challenge)
+	s.mu.Unlock()
+	return s.issueSession(user, "")
+}
+
+// newTwoFactorChallenge is returned by Login instead of a session for
+// users with two-factor authentication.
+func (s *AuthService) newTwoFactorChallenge(user *model.User, username string) (*model.LoginResponse, error) {
+	challenge, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for key, c := range s.challenges {
+		if now.After(c.expires) {
+			delete(s.challenges, key)
+		}
+	}
+	s.challenges[challenge] = &twoFactorChallenge{userID: user.ID, username: username, expires: now.Add(twoFactorChallengeTimeout)}
+	return &model.LoginResponse{TwoFactorRequired: true, Challenge: challenge}, nil
+}
+
+// verifyTwoFactorCode accepts a current TOTP code that was not used before
+// or an unused recovery code.
+func (s *AuthService) verifyTwoFactorCode(user *model.User, code string) error {
+	if !user.TOTPEnabled {
+		return errors.New("two-factor authentication is not enabled")
+	}
+
+	if step, ok := totp.Validate(user.TOTPSecret, code, time.Now()); ok {
+		fresh, err := s.twoFactorRepo.UseStep(user.ID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return errors.New("code has already been used")
+		}
+		return nil
+	}
+
+	used, err := s.twoFactorRepo.UseRecoveryCode(user.ID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return errors.New("invalid code")
+	}
+	return nil
+}
+
+// TwoFactorSetupRequired reports whether the user has to enroll before
+// using the API. Single sign-on users are left to their identity provider.
+func (s *AuthService) TwoFactorSetupRequired(user *model.User) bool {
+	if user.TOTPEnabled || user.AuthProvider == model.AuthProviderOIDC {
+		return false
+	}
+	settings, err := s.SecuritySettings()
+	return err == nil && settings.RequireTwoFactor
+}
+
+// SecuritySettings returns the instance settings, cached after the first
+// read since they are checked on every request.
+func (s *AuthService) SecuritySettings() (model.SecuritySettings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.settings == nil {
+		settings, err := s.twoFactorRepo.GetSecuritySettings()
+		if err != nil {
+			return model.SecuritySettings{}, err
+		}
+		s.settings = settings
+	}
+	return *s.settings, nil
+}
+
+func (s *AuthService) UpdateSecuritySettings(req model.SecuritySettingsRequest) (*model.SecuritySettings, error) {
+	settings := &model.SecuritySettings{RequireTwoFactor: req.RequireTwoFactor}
+	if err := s.twoFactorRepo.SaveSecuritySettings(settings); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.settings = settings
+	s.mu.Unlock()
+	return settings, nil
+}
+
+// newRecoveryCodes returns recovery codes formatted as xxxxx-xxxxx and
+// their hashes for storage.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b)[:10])
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashToken(code)
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/repository"
+	"gogo-scheduler/internal/totp"
+)
+
+func TestTwoFactorCodesAreUsedOnce(t *testing.T) {
+	db := newTestDB(t)
+	userRepo := repository.NewUserRepository(db)
+	auth := newTestAuthService(db)
+
+	user := &model.User{Username: "alice", Role: model.RoleViewer}
+	if err := userRepo.Create(user); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := auth.EnrollTOTP(user); err != nil {
+		t.Fatal(err)
+	}
+	reload := func() *model.User {
+		t.Helper()
+		fresh, err := userRepo.FindByID(uint(user.ID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fresh
+	}
+	code := func(at time.Time) string {
+		t.Helper()
+		c, err := totp.Code(reload().TOTPSecret, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	now := time.Now()
+	if _, err := auth.ActivateTOTP(reload(), code(now)); err != nil {
+		t.Fatalf("ActivateTOTP: %v", err)
+	}
+	// the code that activated two-factor authentication is spent
+	if err := auth.verifyTwoFactorCode(reload(), code(now)); err == nil {
+		t.Error("the activation code was accepted again")
+	}
+	// the next step is accepted once, and earlier steps no longer are
+	next := now.Add(30 * time.Second)
+	if err := auth.verifyTwoFactorCode(reload(), code(next)); err != nil {
+		t.Fatalf("code of the next step: %v", err)
+	}
+	if err := auth.verifyTwoFactorCode(reload(), code(next)); err == nil {
+		t.Error("a code was accepted twice")
+	}
+	if err := auth.verifyTwoFactorCode(reload(), code(now.Add(-30*time.Second))); err == nil {
+		t.Error("a code older than the last used one was accepted")
+	}
+}
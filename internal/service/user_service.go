@@ -0,0 +1,174 @@
+package service
+
+import (
+	"errors"
+
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/repository"
+)
+
+type UserService struct {
+	userRepo       *repository.UserRepository
+	twoFactorRepo  *repository.TwoFactorRepository
+	passwordPolicy PasswordPolicy
+}
+
+func NewUserService(userRepo *repository.UserRepository, twoFactorRepo *repository.TwoFactorRepository, passwordPolicy PasswordPolicy) *UserService {
+	return &UserService{userRepo: userRepo, twoFactorRepo: twoFactorRepo, passwordPolicy: passwordPolicy}
+}
+
+func (s *UserService) ListUsers() ([]model.User, error) {
+	return s.userRepo.List()
+}
+
+func (s *UserService) GetUser(id int64) (*model.User, error) {
+	return s.userRepo.FindByID(uint(id))
+}
+
+func (s *UserService) CreateUser(req model.CreateUserRequest) (*model.User, error) {
+	if req.Role == "" {
+		req.Role = model.RoleViewer
+	}
+	if !req.Role.Valid() {
+		return nil, errors.New("invalid role")
+	}
+
+	existingUser, err := s.userRepo.FindByUsername(req.Username)
+	if err != nil {
+		return nil, err
+	}
+	if existingUser != nil {
+		return nil, errors.New("username already exists")
+	}
+	if err := s.passwordPolicy.Validate(req.Username, req.Password); err != nil {
+		return nil, err
+	}
+
+	user := &model.User{
+		Username:     req.Username,
+		Password:     req.Password,
+		Role:         req.Role,
+		AuthProvider: model.AuthProviderLocal,
+	}
+	if err := user.HashPassword(); err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// SetDisabled enables or disables a user. Disabled users cannot log in and
+// their existing tokens are rejected.
+func (s *UserService) SetDisabled(id int64, disabled bool) (*model.User, error) {
+	user, err := s.userRepo.FindByID(uint(id))
+	if err != nil {
+		return nil, err
+	}
+	if disabled {
+		if err := s.ensureAdminRemains(user); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.userRepo.SetDisabled(user.ID, disabled); err != nil {
+		return nil, err
+	}
+	if disabled {
+		if err := s.userRepo.InvalidateSessions(user.ID); err != nil {
+			return nil, err
+		}
+	}
+	user.Disabled = disabled
+	return user, nil
+}
+
+func (s *UserService) ResetPassword(id int64, password string) error {
+	user, err := s.userRepo.FindByID(uint(id))
+	if err != nil {
+		return err
+	}
+	if err := s.passwordPolicy.Validate(user.Username, password); err != nil {
+		return err
+	}
+
+	user.Password = password
+	if err := user.HashPassword(); err != nil {
+		return err
+	}
+	if err := s.userRepo.SetPassword(user.ID, user.Password); err != nil {
+		return err
+	}
+	return s.userRepo.InvalidateSessions(user.ID)
+}
+
+// ResetTwoFactor turns off two-factor authentication for a user who lost
+// their device. They are logged out and enroll again on their next login
+// if two-factor authentication is required.
+func (s *UserService) ResetTwoFactor(id int64) error {
+	user, err := s.userRepo.FindByID(uint(id))
+	if err != nil {
+		return err
+	}
+	if err := s.twoFactorRepo.Disable(user.ID); err != nil {
+		return err
+	}
+	return s.userRepo.InvalidateSessions(user.ID)
+}
+
+// DeleteUser deletes a user and gives their scripts to heirID, the
+// administrator deleting them.
+func (s *UserService) DeleteUser(id, heirID int64) error {
+	user, err := s.userRepo.FindByID(uint(id))
+	if err != nil {
+		return err
+	}
+	if err := s.ensureAdminRemains(user); err != nil {
+		return err
+	}
+	return s.userRepo.Delete(user.ID, heirID)
+}
+
+// SetRole changes a user's role. The last administrator cannot be demoted.
+func (s *UserService) SetRole(id int64, role model.Role) (*model.User, error) {
+	if !role.Valid() {
+		return nil, errors.New("invalid role")
+	}
+
+	user, err := s.userRepo.FindByID(uint(id))
+	if err != nil {
+		return nil, err
+	}
+	if role != model.RoleAdmin {
+		if err := s.ensureAdminRemains(user); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.userRepo.UpdateRole(user.ID, role); err != nil {
+		return nil, err
+	}
+	// the user signs in again to pick up the new role
+	if err := s.userRepo.InvalidateSessions(user.ID); err != nil {
+		return nil, err
+	}
+	user.Role = role
+	return user, nil
+}
+
+// ensureAdminRemains returns an error if user is the only enabled
+// administrator.
+func (s *UserService) ensureAdminRemains(user *model.User) error {
+	if user.Role != model.RoleAdmin || user.Disabled {
+		return nil
+	}
+	admins, err := s.userRepo.CountByRole(model.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	if admins <= 1 {
+		return errors.New("cannot remove the last administrator")
+	}
+	return nil
+}
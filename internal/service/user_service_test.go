@@ -0,0 +1,42 @@
+package service
+
+import (
+	"slices"
+	"testing"
+
+	"gogo-scheduler/internal/model"
+	"gogo-scheduler/internal/repository"
+)
+
+func TestDeleteUserTransfersScripts(t *testing.T) {
+	db := newTestDB(t)
+	userRepo := repository.NewUserRepository(db)
+	scriptRepo := repository.NewScriptRepository(db)
+	s := NewUserService(userRepo, repository.NewTwoFactorRepository(db), DefaultPasswordPolicy())
+
+	admin := &model.User{Username: "root", Role: model.RoleAdmin}
+	leaver := &model.User{Username: "leaver", Role: model.RoleEditor}
+	approver := &model.User{Username: "approver", Role: model.RoleEditor}
+	for _, user := range []*model.User{admin, leaver, approver} {
+		if err := userRepo.Create(user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	owned := &model.Script{Name: "owned", Type: "shell", OwnerID: leaver.ID}
+	gated := &model.Script{Name: "gated", Type: "shell", OwnerID: approver.ID, RequiresApproval: true, Approvers: []int64{leaver.ID, approver.ID}}
+	for _, script := range []*model.Script{owned, gated} {
+		if err := scriptRepo.Create(script); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.DeleteUser(leaver.ID, admin.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := scriptRepo.GetByID(owned.ID); err != nil || got.OwnerID != admin.ID {
+		t.Errorf("owner of the leaver's script = %d (%v), want %d", got.OwnerID, err, admin.ID)
+	}
+	if got, err := scriptRepo.GetByID(gated.ID); err != nil || !slices.Equal(got.Approvers, []int64{approver.ID}) || got.OwnerID != approver.ID {
+		t.Errorf("gated script = owner %d, approvers %v (%v)", got.OwnerID, got.Approvers, err)
+	}
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"gogo-scheduler/internal/model"
+)
+
+// WebhookSender posts notifications as JSON to the channel's URL, either as
+// is or formatted for a chat. If the channel has a secret, the
+// X-Gogo-Signature-256 header holds "sha256=" followed by the hex
+// HMAC-SHA256 of the body; DingTalk and Feishu messages are instead signed
+// the way those chats expect.
+type WebhookSender struct {
+	client *http.Client
+}
+
+func NewWebhookSender(client *http.Client) *WebhookSender {
+	return &WebhookSender{client: client}
+}
+
+// ErrDestinationNotAllowed is returned when a webhook resolves to an
+// internal address that is not allowed.
+var ErrDestinationNotAllowed = errors.New("webhook destination is not allowed")
+
+// newWebhookClient returns a client that refuses to connect to loopback,
+// private, link-local and other non-public addresses outside of allowed.
+// The address is checked when dialing, after name resolution and for every
+// redirect, so neither DNS nor redirects can lead to an internal service.
+func newWebhookClient(allowed []*net.IPNet) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicAddress(ip) && !inNetworks(ip, allowed) {
+				return fmt.Errorf("%w: %s", ErrDestinationNotAllowed, host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: 10 * time.Second, Transport: transport}
+}
+
+func publicAddress(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+func inNetworks(ip net.IP, networks []*net.IPNet) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *WebhookSender) Send(ctx context.Context, channel *model.NotificationChannel, recipients []string, n *model.Notification) (int, error) {
+	format := channel.Webhook.Format
+	webhookURL := channel.Webhook.URL
+	var payload any = n
+	if build, ok := chatFormats[format]; ok {
+		message := build(n)
+		if channel.Secret != "" {
+			switch format {
+			case model.WebhookFormatDingTalk:
+				signed, err := signDingTalk(webhookURL, channel.Secret, time.Now())
+				if err != nil {
+					return 0, err
+				}
+				webhookURL = signed
+			case model.WebhookFormatFeishu:
+				signFeishu(message, channel.Secret, time.Now())
+			}
+		}
+		payload = message
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	for name, value := range channel.Webhook.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gogo-scheduler")
+	req.Header.Set("X-Gogo-Event", n.Event)
+	if channel.Secret != "" && format != model.WebhookFormatDingTalk && format != model.WebhookFormatFeishu {
+		req.Header.Set("X-Gogo-Signature-256", "sha256="+signPayload(channel.Secret, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status: %s", resp.Status)
+	}
+	// DingTalk and Feishu report errors with a 200 status
+	if err := chatError(format, respBody); err != nil {
+		return resp.StatusCode, err
+	}
+	return resp.StatusCode, nil
+}
+
+func signPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gogo-scheduler/internal/model"
+)
+
+func TestWebhookClientBlocksInternalDestinations(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer server.Close()
+	channel := &model.NotificationChannel{Type: model.ChannelWebhook, Webhook: &model.WebhookConfig{URL: server.URL}}
+	n := &model.Notification{Event: EventTest, Timestamp: time.Now()}
+
+	status, err := NewWebhookSender(newWebhookClient(nil)).Send(context.Background(), channel, nil, n)
+	if !errors.Is(err, ErrDestinationNotAllowed) || status != 0 || hits.Load() != 0 {
+		t.Fatalf("Send to loopback = %d, %v with %d requests; want ErrDestinationNotAllowed", status, err, hits.Load())
+	}
+	if retryable(model.ChannelWebhook, status, err) {
+		t.Error("a blocked destination is retried")
+	}
+
+	_, loopback, _ := net.ParseCIDR("127.0.0.0/8")
+	status, err = NewWebhookSender(newWebhookClient([]*net.IPNet{loopback})).Send(context.Background(), channel, nil, n)
+	if err != nil || status != http.StatusOK || hits.Load() != 1 {
+		t.Errorf("Send to an allowed network = %d, %v with %d requests", status, err, hits.Load())
+	}
+}
+
+func TestPublicAddress(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"93.184.216.34":    true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"0.0.0.0":          false,
+		"::1":              false,
+		"fe80::1":          false,
+		"fd00:ec2::254":    false,
+		"::ffff:127.0.0.1": false,
+	} {
+		if got := publicAddress(net.ParseIP(addr)); got != want {
+			t.Errorf("publicAddress(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"fmt"
+	"sync"
+)
+
+// CappedBuffer is an io.Writer that keeps at most max bytes: the first half
+// of the output and the most recent half. Anything in between is dropped and
+// replaced by a marker when the buffer is read. A max of 0 keeps everything.
+type CappedBuffer struct {
+	mu      sync.Mutex
+	max     int
+	head    []byte
+	tail    []byte // ring buffer once full
+	tailPos int
+	total   int64
+}
+
+func NewCappedBuffer(max int) *CappedBuffer {
+	return &CappedBuffer{max: max}
+}
+
+func (b *CappedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := len(p)
+	b.total += int64(n)
+
+	if b.max <= 0 {
+		b.head = append(b.head, p...)
+		return n, nil
+	}
+
+	headMax := b.max / 2
+	if room := headMax - len(b.head); room > 0 {
+		if room > len(p) {
+			room = len(p)
+		}
+		b.head = append(b.head, p[:room]...)
+		p = p[room:]
+	}
+
+	tailMax := b.max - headMax
+	for len(p) > 0 {
+		if len(b.tail) < tailMax {
+			room := tailMax - len(b.tail)
+			if room > len(p) {
+				room = len(p)
+			}
+			b.tail = append(b.tail, p[:room]...)
+			p = p[room:]
+			continue
+		}
+		copied := copy(b.tail[b.tailPos:], p)
+		b.tailPos = (b.tailPos + copied) % tailMax
+		p = p[copied:]
+	}
+	return n, nil
+}
+
+// Truncated reports whether any output was dropped.
+func (b *CappedBuffer) Truncated() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.max > 0 && b.total > int64(b.max)
+}
+
+// Bytes returns the retained output, with a marker where output was dropped.
+func (b *CappedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	out := make([]byte, 0, len(b.head)+len(b.tail)+64)
+	out = append(out, b.head...)
+	if dropped := b.total - int64(len(b.head)+len(b.tail)); dropped > 0 {
+		out = append(out, fmt.Sprintf("\n... [%d bytes truncated] ...\n", dropped)...)
+	}
+	out = append(out, b.tail[b.tailPos:]...)
+	out = append(out, b.tail[:b.tailPos]...)
+	return out
+}
+
+func (b *CappedBuffer) String() string {
+	return string(b.Bytes())
+}
@@ -0,0 +1,201 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 is an in-memory stand-in for an S3-compatible service that keeps
+// objects of one bucket and honours single byte ranges.
+type fakeS3 struct {
+	t      *testing.T
+	bucket string
+	ranges bool // whether Range headers are honoured
+
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newFakeS3(t *testing.T, bucket string) (*fakeS3, *httptest.Server) {
+	f := &fakeS3{t: t, bucket: bucket, ranges: true, objects: map[string][]byte{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || r.Header.Get("x-amz-date") == "" {
+		http.Error(w, "missing signature", http.StatusForbidden)
+		return
+	}
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+f.bucket+"/")
+	if !ok {
+		http.Error(w, "no such bucket", http.StatusNotFound)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.objects[key] = body
+	case http.MethodGet:
+		body, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "no such key", http.StatusNotFound)
+			return
+		}
+		byteRange := r.Header.Get("Range")
+		if byteRange == "" || !f.ranges {
+			w.Write(body)
+			return
+		}
+		var start, end int
+		if n, _ := fmt.Sscanf(byteRange, "bytes=%d-%d", &start, &end); n < 2 {
+			end = len(body) - 1
+		}
+		if start >= len(body) {
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		end = min(end, len(body)-1)
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(body)))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(body[start : end+1])
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newTestS3Store(t *testing.T, endpoint string) *S3Store {
+	t.Helper()
+	store, err := NewS3Store(S3Config{Endpoint: endpoint, Bucket: "logs", AccessKey: "AKID", SecretKey: "secret", Prefix: "gogo/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func readAll(t *testing.T, rc io.ReadCloser, err error) string {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestS3Store(t *testing.T) {
+	fake, srv := newFakeS3(t, "logs")
+	store := newTestS3Store(t, srv.URL)
+	ctx := context.Background()
+
+	const key = "tasks/1/output log.txt"
+	const body = "0123456789"
+	if err := store.Put(ctx, key, strings.NewReader(body), int64(len(body))); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, ok := fake.objects["gogo/"+key]; !ok {
+		t.Fatalf("object not stored below the prefix: %v", fake.objects)
+	}
+
+	rc, err := store.Get(ctx, key)
+	if got := readAll(t, rc, err); got != body {
+		t.Errorf("Get = %q, want %q", got, body)
+	}
+
+	for _, tc := range []struct {
+		offset, length int64
+		want           string
+	}{
+		{0, 3, "012"},
+		{4, 0, "456789"},
+		{8, 5, "89"},
+		{10, 0, ""},
+	} {
+		rc, err := store.GetRange(ctx, key, tc.offset, tc.length)
+		if got := readAll(t, rc, err); got != tc.want {
+			t.Errorf("GetRange(%d, %d) = %q, want %q", tc.offset, tc.length, got, tc.want)
+		}
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("deleting a missing key: %v", err)
+	}
+}
+
+func TestS3StoreGetRangeWithoutRangeSupport(t *testing.T) {
+	fake, srv := newFakeS3(t, "logs")
+	fake.ranges = false
+	store := newTestS3Store(t, srv.URL)
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "k", strings.NewReader("0123456789"), 10); err != nil {
+		t.Fatal(err)
+	}
+	rc, err := store.GetRange(ctx, "k", 2, 3)
+	if got := readAll(t, rc, err); got != "234" {
+		t.Errorf("GetRange = %q, want %q", got, "234")
+	}
+}
+
+func TestS3StoreError(t *testing.T) {
+	_, srv := newFakeS3(t, "logs")
+	store, err := NewS3Store(S3Config{Endpoint: srv.URL, Bucket: "logs", AccessKey: "other", SecretKey: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Put(context.Background(), "k", strings.NewReader("x"), 1)
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Put with rejected credentials: err = %v, want a 403 error", err)
+	}
+}
+
+func TestLocalStoreGetRange(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := store.Put(ctx, "a/b.log", strings.NewReader("0123456789"), 10); err != nil {
+		t.Fatal(err)
+	}
+
+	rc, err := store.GetRange(ctx, "a/b.log", 3, 4)
+	if got := readAll(t, rc, err); got != "3456" {
+		t.Errorf("GetRange = %q, want %q", got, "3456")
+	}
+	rc, err = store.GetRange(ctx, "a/b.log", 7, 0)
+	if got := readAll(t, rc, err); got != "789" {
+		t.Errorf("GetRange to the end = %q, want %q", got, "789")
+	}
+	if _, err := store.GetRange(ctx, "missing", 0, 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetRange of a missing key: err = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrNotFound is returned by Get when the key does not exist.
+var ErrNotFound = errors.New("object not found")
+
+// Store is a flat key/value blob store used for task logs and artifacts.
+// Keys are slash-separated paths such as "tasks/42/output.log".
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// GetRange reads length bytes of the key starting at offset, or the
+	// rest of it if length is 0.
+	GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	// Delete removes the key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+type Config struct {
+	Backend string // local (default) or s3
+	Dir     string // root directory for the local backend
+	S3      S3Config
+}
+
+// limitedReadCloser closes the object it limits reads from.
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// New creates the Store selected by cfg.Backend.
+func New(cfg Config) (Store, error) {
+	switch cfg.Backend {
+	case "", "local":
+		return NewLocalStore(cfg.Dir)
+	case "s3":
+		return NewS3Store(cfg.S3)
+	default:
+		return nil, fmt.Errorf("unsupported storage backend: %s", cfg.Backend)
+	}
+}
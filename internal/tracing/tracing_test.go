@@ -0,0 +1,163 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// record installs an in-memory exporter and returns a function that
+// flushes and returns the spans ended so far.
+func record(t *testing.T) func() tracetest.SpanStubs {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := Install(exporter)
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+	return func() tracetest.SpanStubs {
+		if err := provider.ForceFlush(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return exporter.GetSpans()
+	}
+}
+
+func findSpan(spans tracetest.SpanStubs, name string) *tracetest.SpanStub {
+	for i := range spans {
+		if spans[i].Name == name {
+			return &spans[i]
+		}
+	}
+	return nil
+}
+
+func attr(span *tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestStartAndEnd(t *testing.T) {
+	spans := record(t)
+
+	ctx, parent := Start(context.Background(), "parent")
+	_, child := Start(ctx, "child")
+	End(child, errors.New("boom"))
+	End(parent, nil)
+
+	got := spans()
+	if len(got) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(got))
+	}
+	c, p := findSpan(got, "child"), findSpan(got, "parent")
+	if c == nil || p == nil {
+		t.Fatalf("spans = %v", got.Snapshots())
+	}
+	if c.Parent.SpanID() != p.SpanContext.SpanID() {
+		t.Error("child span is not a child of the parent")
+	}
+	if c.Status.Code != codes.Error || c.Status.Description != "boom" || len(c.Events) != 1 {
+		t.Errorf("child status = %+v with %d events, want the recorded error", c.Status, len(c.Events))
+	}
+	if p.Status.Code != codes.Unset {
+		t.Errorf("parent status = %+v, want unset", p.Status)
+	}
+	if name, _ := p.Resource.Set().Value("service.name"); name.AsString() != serviceName {
+		t.Errorf("service.name = %q, want %q", name.AsString(), serviceName)
+	}
+}
+
+func TestEnvironAndExtract(t *testing.T) {
+	record(t)
+
+	if env := Environ(context.Background()); len(env) != 0 {
+		t.Errorf("Environ without a span = %v, want none", env)
+	}
+
+	ctx, span := Start(context.Background(), "run")
+	defer span.End()
+	env := Environ(ctx)
+	if len(env) != 1 || !strings.HasPrefix(env[0], "TRACEPARENT=") {
+		t.Fatalf("Environ = %v, want a TRACEPARENT", env)
+	}
+	traceparent := strings.TrimPrefix(env[0], "TRACEPARENT=")
+	if !strings.Contains(traceparent, span.SpanContext().TraceID().String()) {
+		t.Errorf("TRACEPARENT %s does not carry trace %s", traceparent, span.SpanContext().TraceID())
+	}
+
+	// a process receiving the header continues the same trace
+	extracted := Extract(context.Background(), propagation.MapCarrier{"traceparent": traceparent})
+	remote := trace.SpanContextFromContext(extracted)
+	if !remote.IsRemote() || remote.TraceID() != span.SpanContext().TraceID() || remote.SpanID() != span.SpanContext().SpanID() {
+		t.Errorf("extracted span context %+v does not match %+v", remote, span.SpanContext())
+	}
+}
+
+func TestGormPlugin(t *testing.T) {
+	spans := record(t)
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(GormPlugin{}); err != nil {
+		t.Fatal(err)
+	}
+	type item struct {
+		ID   int64
+		Name string
+	}
+	if err := db.AutoMigrate(&item{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// calls without a trace are not traced
+	if err := db.Create(&item{Name: "untraced"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, request := Start(context.Background(), "request")
+	var found item
+	err = db.WithContext(ctx).Where("name = ?", "missing").First(&found).Error
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("First: %v", err)
+	}
+	request.End()
+
+	got := spans()
+	if len(got) != 2 {
+		t.Fatalf("recorded %v, want the request and one query", got.Snapshots())
+	}
+	query := findSpan(got, "gorm.query")
+	if query == nil {
+		t.Fatalf("no gorm.query span in %v", got.Snapshots())
+	}
+	if query.Parent.SpanID() != request.SpanContext().SpanID() {
+		t.Error("query span is not a child of the request")
+	}
+	if query.SpanKind != trace.SpanKindClient {
+		t.Errorf("query span kind = %v", query.SpanKind)
+	}
+	if stmt := attr(query, "db.query.text").AsString(); !strings.Contains(stmt, "name = ?") {
+		t.Errorf("db.query.text = %q", stmt)
+	}
+	if table := attr(query, "db.collection.name").AsString(); table != "items" {
+		t.Errorf("db.collection.name = %q, want items", table)
+	}
+	// a missing record is not an error of the database
+	if query.Status.Code == codes.Error {
+		t.Errorf("query status = %+v", query.Status)
+	}
+}